@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/w3qxst1ck/cs2-grenades/internal/data"
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+// auditExportTimeout меньше WriteTimeout сервера, чтобы выгрузка завершилась до обрыва соединения
+const auditExportTimeout = 25 * time.Second
+
+// audit записывает изменение сущности в журнал аудита. Изменение к этому моменту уже сохранено,
+// поэтому ошибка записи журнала только логируется. r равен nil у фоновых задач
+func (app *application) audit(r *http.Request, action, entityType string, entityID int64, before, after interface{}) {
+	entry := &data.AuditEntry{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Before:     auditSnapshot(before),
+		After:      auditSnapshot(after),
+	}
+
+	if r != nil {
+		entry.ActorID = app.contextGetUser(r).ID
+		entry.RequestID = app.contextGetRequestID(r)
+
+		if key := app.contextGetAPIKey(r); key != nil {
+			entry.APIKeyID = key.ID
+		}
+
+		if ip, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			entry.IP = ip
+		}
+	}
+
+	err := app.models.AuditLog.Insert(entry)
+	if err != nil {
+		err = fmt.Errorf("audit log: %s %s %d: %w", action, entityType, entityID, err)
+		if r != nil {
+			app.logError(r, err)
+		} else {
+			app.logger.Print(err)
+		}
+	}
+}
+
+// auditSnapshot сериализует сущность в JSON. Состояние "до" снимается этой функцией
+// до того, как хендлер начнет менять сущность
+func auditSnapshot(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+
+	// typed nil и пустые снимки в журнал не пишутся
+	js, err := json.Marshal(v)
+	if err != nil || string(js) == "null" {
+		return nil
+	}
+
+	return js
+}
+
+func (app *application) readAuditSearch(qs url.Values, v *validator.Validator) data.AuditSearch {
+	search := data.AuditSearch{
+		Actions:     data.ParseListFilter(app.readString(qs, "action", "")),
+		EntityTypes: data.ParseListFilter(app.readString(qs, "entity_type", "")),
+		EntityID:    int64(app.readInt(qs, "entity_id", 0, v)),
+		ActorID:     int64(app.readInt(qs, "actor_id", 0, v)),
+		RequestID:   app.readString(qs, "request_id", ""),
+		From:        app.readTime(qs, "from", v),
+		To:          app.readTime(qs, "to", v),
+	}
+
+	data.ValidateAuditSearch(v, search)
+
+	return search
+}
+
+// getAuditLogHandler - журнал аудита с фильтрами, например ?entity_type=grenade&entity_id=42&action=delete
+func (app *application) getAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	var filters data.Filters
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+	search := app.readAuditSearch(qs, v)
+	filters.Page = app.readInt(qs, "page", 1, v)
+	filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	filters.Sort = app.readString(qs, "sort", "-id")
+	filters.SortSafeList = []string{"id", "created_at", "-id", "-created_at"}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	entries, metadata, err := app.models.AuditLog.GetAll(search, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"entries": entries, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// exportAuditLogHandler выгружает журнал в NDJSON, по записи на строку, с теми же фильтрами, что и список.
+// Большие выгрузки нужно делить по from/to, иначе их оборвет таймаут
+func (app *application) exportAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	search := app.readAuditSearch(r.URL.Query(), v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), auditExportTimeout)
+	defer cancel()
+
+	// заголовки отправляются с первой записью, до этого ошибку еще можно вернуть обычным ответом
+	started := false
+	writeHeader := func() {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="audit.ndjson"`)
+		w.WriteHeader(http.StatusOK)
+		started = true
+	}
+
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	count := 0
+
+	err := app.models.AuditLog.Export(ctx, search, func(entry *data.AuditEntry) error {
+		if !started {
+			writeHeader()
+		}
+
+		count++
+		if flusher != nil && count%100 == 0 {
+			flusher.Flush()
+		}
+
+		return enc.Encode(entry)
+	})
+	if err != nil {
+		if !started {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		// ответ уже отправляется, выгрузка останется неполной
+		app.logError(r, err)
+		return
+	}
+
+	if !started {
+		writeHeader()
+	}
+}
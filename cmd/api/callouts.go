@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/w3qxst1ck/cs2-grenades/internal/data"
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+// getAllCalloutsHandler - каллауты для подсказок поиска, ?map= ограничивает одной картой
+func (app *application) getAllCalloutsHandler(w http.ResponseWriter, r *http.Request) {
+	csMap := app.readString(r.URL.Query(), "map", "")
+
+	callouts, err := app.models.Callouts.GetAll(csMap)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"callouts": callouts}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createCalloutHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Map  string `json:"map"`
+		Name string `json:"name"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	callout := &data.Callout{
+		Map:  input.Map,
+		Name: input.Name,
+	}
+
+	v := validator.New()
+	if data.ValidateCallout(callout, v); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	err = app.models.Callouts.Insert(callout)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateCallout):
+			v.AddError("name", "a callout with this name already exists on this map")
+			app.failedValidationResponse(w, r, v.Erorrs)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, "create", "callout", callout.ID, nil, callout)
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"callout": callout}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteCalloutHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	callout, err := app.models.Callouts.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Callouts.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, "delete", "callout", callout.ID, callout, nil)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "callout successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
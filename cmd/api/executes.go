@@ -0,0 +1,333 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/w3qxst1ck/cs2-grenades/internal/data"
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+type executeItemInput struct {
+	GrenadeID     int64 `json:"grenade_id"`
+	PlayerSlot    int   `json:"player_slot"`
+	ThrowOffsetMs int   `json:"throw_offset_ms"`
+}
+
+func (app *application) getExecuteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	execute, err := app.models.Executes.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"execute": execute}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getAllExecutesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Map  string
+		Side string
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+	input.Map = app.readString(qs, "map", "")
+	input.Side = app.readString(qs, "side", "")
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.SortSafeList = []string{"id", "name", "map", "side", "-id", "-name", "-map", "-side"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	executes, metadata, err := app.models.Executes.GetAll(input.Map, input.Side, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"executes": executes, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createExecuteHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name        string             `json:"name"`
+		Map         string             `json:"map"`
+		Side        string             `json:"side"`
+		Description string             `json:"description"`
+		Items       []executeItemInput `json:"items"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	execute := &data.Execute{
+		Name:        input.Name,
+		Map:         input.Map,
+		Side:        input.Side,
+		Description: input.Description,
+		Items:       executeItems(input.Items),
+	}
+
+	if !app.validateExecute(w, r, execute) {
+		return
+	}
+
+	err = app.models.Executes.Insert(execute)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.audit(r, "create", "execute", execute.ID, nil, execute)
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/executes/%d", execute.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"execute": execute}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateExecuteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	execute, err := app.models.Executes.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	before := auditSnapshot(execute)
+
+	var input struct {
+		Name        *string            `json:"name"`
+		Map         *string            `json:"map"`
+		Side        *string            `json:"side"`
+		Description *string            `json:"description"`
+		Items       []executeItemInput `json:"items"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// Get не возвращает скрытые items, их карту и сторону проверить нельзя,
+	// поэтому при смене карты или стороны список items передается заново
+	mapChanged := input.Map != nil && *input.Map != execute.Map
+	sideChanged := input.Side != nil && *input.Side != execute.Side
+
+	if (mapChanged || sideChanged) && input.Items == nil {
+		v := validator.New()
+		v.AddError("items", "must be provided when map or side changes")
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	if input.Name != nil {
+		execute.Name = *input.Name
+	}
+
+	if input.Map != nil {
+		execute.Map = *input.Map
+	}
+
+	if input.Side != nil {
+		execute.Side = *input.Side
+	}
+
+	if input.Description != nil {
+		execute.Description = *input.Description
+	}
+
+	// items заменяются целиком
+	if input.Items != nil {
+		execute.Items = executeItems(input.Items)
+	}
+
+	if !app.validateExecute(w, r, execute) {
+		return
+	}
+
+	err = app.models.Executes.Update(execute, input.Items != nil)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, "update", "execute", execute.ID, before, execute)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"execute": execute}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteExecuteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	execute, err := app.models.Executes.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Executes.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, "delete", "execute", execute.ID, execute, nil)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "execute successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getExecuteTimelineHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	// entry_ms - момент выхода на плент, до которого покрытие не должно пропадать
+	var entryMs *int
+	if qs := r.URL.Query(); qs.Has("entry_ms") {
+		entry := app.readInt(qs, "entry_ms", 0, v)
+		v.Check(entry >= 0, "entry_ms", "must not be negative")
+		entryMs = &entry
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	execute, err := app.models.Executes.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	timings, err := app.models.GrenadeTimings.GetAll()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	timeline := data.BuildTimeline(execute, timings, entryMs)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"timeline": timeline}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// validateExecute подгружает гранаты execute и проверяет его,
+// при ошибке сам пишет ответ и возвращает false
+func (app *application) validateExecute(w http.ResponseWriter, r *http.Request, execute *data.Execute) bool {
+	grenades, err := app.models.Grenades.GetByIDs(execute.GrenadeIDs())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	// чужие черновики не должны отличаться от несуществующих раскидок
+	err = app.hideUnpublishedGrenades(r, grenades)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	v := validator.New()
+	if data.ValidateExecute(execute, grenades, v); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return false
+	}
+
+	for _, item := range execute.Items {
+		item.Grenade = grenades[item.GrenadeID]
+	}
+
+	return true
+}
+
+func executeItems(input []executeItemInput) []*data.ExecuteItem {
+	items := make([]*data.ExecuteItem, len(input))
+	for i := range input {
+		items[i] = &data.ExecuteItem{
+			GrenadeID:     input[i].GrenadeID,
+			PlayerSlot:    input[i].PlayerSlot,
+			ThrowOffsetMs: input[i].ThrowOffsetMs,
+		}
+	}
+	return items
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 
 	"github.com/w3qxst1ck/cs2-grenades/internal/data"
@@ -11,21 +12,9 @@ import (
 )
 
 func (app *application) getGrenadeHandler(w http.ResponseWriter, r *http.Request) {
-	id, err := app.readIDParam(r)
-	if err != nil {
-		app.notFoundResponse(w, r)
-		return
-	}
-
-	// get grenade
-	grenade, err := app.models.Grenades.Get(id)
-	if err != nil {
-		switch {
-		case errors.Is(err, data.ErrRecordNotFound):
-			app.notFoundResponse(w, r)
-		default:
-			app.serverErrorResponse(w, r, err)
-		}
+	// get grenade, неопубликованные раскидки видят только автор и модераторы
+	grenade, ok := app.readVisibleGrenade(w, r)
+	if !ok {
 		return
 	}
 
@@ -41,7 +30,39 @@ func (app *application) getGrenadeHandler(w http.ResponseWriter, r *http.Request
 
 	grenade.Images = images
 
-	app.cache.Set(r.URL.Path, envelope{"grenade": grenade}, 0)
+	tags, err := app.models.Tags.GetByGrenadeIDs([]int64{grenade.ID})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	grenade.Tags = tags[grenade.ID]
+
+	// other lineups for the same target
+	if grenade.TargetID != 0 {
+		variants, err := app.models.Grenades.GetByTargetID(grenade.TargetID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		grenade.Variants = []*data.Grenade{}
+		for _, variant := range publishedGrenades(variants) {
+			if variant.ID != grenade.ID {
+				grenade.Variants = append(grenade.Variants, variant)
+			}
+		}
+	}
+
+	grenade.Relations, err = app.models.Relations.GetByGrenadeID(grenade.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if grenade.Status == "published" {
+		app.cache.Set(r.URL.Path, envelope{"grenade": grenade}, 0)
+	}
 
 	err = app.writeJSON(w, http.StatusOK, envelope{"grenade": grenade}, nil)
 	if err != nil {
@@ -51,11 +72,14 @@ func (app *application) getGrenadeHandler(w http.ResponseWriter, r *http.Request
 
 func (app *application) createGrenadeHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Map         string `json:"map"`
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		Type        string `json:"type"`
-		Side        string `json:"side"`
+		Map         string   `json:"map"`
+		Title       string   `json:"title"`
+		Description string   `json:"description"`
+		Type        string   `json:"type"`
+		Side        string   `json:"side"`
+		TargetID    int64    `json:"target_id"`
+		Credit      string   `json:"credit"`
+		Tags        []string `json:"tags"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -70,21 +94,38 @@ func (app *application) createGrenadeHandler(w http.ResponseWriter, r *http.Requ
 		Description: input.Description,
 		Type:        input.Type,
 		Side:        input.Side,
+		TargetID:    input.TargetID,
+		Credit:      input.Credit,
+		CreatedBy:   app.contextGetUser(r).ID,
+		Tags:        input.Tags,
 	}
 
 	v := validator.New()
 	data.ValidateGrenade(grenade, v)
+	data.ValidateTagNames(input.Tags, v)
 	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Erorrs)
 		return
 	}
 
+	if !app.validateGrenadeTarget(w, r, grenade) {
+		return
+	}
+
 	err = app.models.Grenades.Insert(grenade)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrUnknownTag):
+			v.AddError("tags", "must contain only existing tags")
+			app.failedValidationResponse(w, r, v.Erorrs)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
+	app.audit(r, "create", "grenade", grenade.ID, nil, grenade)
+
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/v1/grenades/%d", grenade.ID))
 
@@ -112,12 +153,23 @@ func (app *application) updateGrenadeHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if !app.requireGrenadeOwner(w, r, grenade) || !app.requireGrenadeEditable(w, r, grenade) {
+		return
+	}
+
+	before := auditSnapshot(grenade)
+
 	var input struct {
-		Map         *string `json:"map"`
-		Title       *string `json:"title"`
-		Type        *string `json:"type"`
-		Side        *string `json:"side"`
-		Description *string `json:"description"`
+		Map         *string  `json:"map"`
+		Title       *string  `json:"title"`
+		Type        *string  `json:"type"`
+		Side        *string  `json:"side"`
+		Description *string  `json:"description"`
+		TargetID    *int64   `json:"target_id"`
+		Credit      *string  `json:"credit"`
+		Tags        []string `json:"tags"`
+		// Note - описание правки для истории ревизий
+		Note string `json:"note"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -146,23 +198,48 @@ func (app *application) updateGrenadeHandler(w http.ResponseWriter, r *http.Requ
 		grenade.Side = *input.Side
 	}
 
+	// target_id: 0 отвязывает гранату от цели
+	if input.TargetID != nil {
+		grenade.TargetID = *input.TargetID
+	}
+
+	if input.Credit != nil {
+		grenade.Credit = *input.Credit
+	}
+
+	grenade.UpdatedBy = app.contextGetUser(r).ID
+
 	v := validator.New()
-	if data.ValidateGrenade(grenade, v); !v.Valid() {
+	data.ValidateGrenade(grenade, v)
+	data.ValidateTagNames(input.Tags, v)
+	data.ValidateRevisionNote(v, input.Note)
+	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Erorrs)
 		return
 	}
 
-	err = app.models.Grenades.Update(grenade)
+	if !app.validateGrenadeTarget(w, r, grenade) {
+		return
+	}
+
+	// tags: null оставляет теги без изменений, tags: [] удаляет все теги
+	err = app.models.Grenades.Update(grenade, input.Note, input.Tags)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
 			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrUnknownTag):
+			v.AddError("tags", "must contain only existing tags")
+			app.failedValidationResponse(w, r, v.Erorrs)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
+	app.cache.Delete(fmt.Sprintf("/v1/grenades/%d", grenade.ID))
+	app.audit(r, "update", "grenade", grenade.ID, before, grenade)
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"grenade": grenade}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -176,60 +253,158 @@ func (app *application) deleteGrenadeHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	images, err := app.models.Images.GetByGrenadeID(id)
+	grenade, err := app.models.Grenades.Get(id)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
-	// deleting from S3 storage
-	if err = app.deleteImagesFromStorage(images); err != nil {
-		app.serverErrorResponse(w, r, err)
+	if !app.requireGrenadeOwner(w, r, grenade) {
 		return
 	}
 
-	err = app.models.Grenades.Delete(id)
+	before := auditSnapshot(grenade)
+
+	// раскидка переносится в корзину, изображения в хранилище удалит очистка корзины
+	err = app.models.Grenades.Delete(grenade, app.contextGetUser(r).ID)
 	if err != nil {
 		switch {
-		case errors.Is(err, data.ErrRecordNotFound):
-			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
+	app.clearGrenadeCache(grenade.Map)
+	app.audit(r, "delete", "grenade", grenade.ID, before, grenade)
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"message": "grenade successfully deleted"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
+// clearGrenadeCache удаляет из кэша все выборки, в которые раскидка могла попасть или пропасть
+// при смене видимости: саму раскидку, страницы списка, подсказки поиска и граф карты.
+// Ключи страниц и подсказок содержат query string, поэтому удаляются по префиксу
+func (app *application) clearGrenadeCache(csMap string) {
+	app.cache.Delete(fmt.Sprintf("/v1/maps/%s/graph", csMap))
+
+	for key := range app.cache.Items() {
+		if strings.HasPrefix(key, "/v1/grenades/") || strings.HasPrefix(key, "/v1/search/suggest") {
+			app.cache.Delete(key)
+		}
+	}
+}
+
 func (app *application) getAllGrenadesHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Map  string
-		Side string
-		Type string
+		data.GrenadeSearch
 		data.Filters
 	}
 
+	v := validator.New()
+
 	qs := r.URL.Query()
-	input.Map = app.readString(qs, "map", "")
-	input.Side = app.readString(qs, "side", "")
-	input.Type = app.readString(qs, "type", "")
+	input.Maps = data.ParseListFilter(app.readString(qs, "map", ""))
+	input.Sides = data.ParseListFilter(app.readString(qs, "side", ""))
+	input.Types = data.ParseListFilter(app.readString(qs, "type", ""))
+	// по умолчанию сломанные раскидки не показываются, ?verification=broken вернет только их
+	input.Verifications = data.ParseListFilter(app.readString(qs, "verification", "!broken"))
+	// публично видны только опубликованные раскидки, остальные статусы - для модераторов и автора в ?author
+	input.Statuses = data.ParseListFilter(app.readString(qs, "status", "published"))
+	input.AuthorID = int64(app.readInt(qs, "author", 0, v))
+	input.Q = app.readString(qs, "q", "")
+	input.Tags = data.ParseListFilter(app.readString(qs, "tags", "")).Include
+	tagsMatch := app.readString(qs, "tags_match", "any")
+	input.TagsMatchAll = tagsMatch == "all"
+	collapse := app.readString(qs, "collapse", "")
+	input.CollapseTargets = collapse == "target"
+
+	data.ValidateTagNames(input.Tags, v)
+	v.Check(v.In(tagsMatch, []string{"any", "all"}), "tags_match", "value of tags_match must be any or all")
+	v.Check(v.In(collapse, []string{"", "target"}), "collapse", "value of collapse must be target")
+	data.ValidateListFilter(v, "map", input.Maps, nil)
+	data.ValidateListFilter(v, "side", input.Sides, data.GrenadeSides)
+	data.ValidateListFilter(v, "type", input.Types, data.GrenadeTypes)
+	data.ValidateListFilter(v, "verification", input.Verifications, data.VerificationStatuses)
+	data.ValidateListFilter(v, "status", input.Statuses, data.GrenadeStatuses)
+	v.Check(input.AuthorID >= 0, "author", "must be a positive integer")
+	v.Check(len(input.Q) <= 200, "q", "must not be grater than 200 bytes")
+
+	if filter := app.readString(qs, "filter", ""); filter != "" {
+		safeList := []string{"map", "side", "type", "title", "text", "tag", "technique", "verification"}
+
+		query, err := data.ParseFilterQuery(filter, safeList, "text")
+		if err != nil {
+			v.AddError("filter", err.Error())
+		}
+		input.Filter = query
+	}
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 	input.Filters.Sort = app.readString(qs, "sort", "id")
-	input.Filters.SortSafeList = []string{"id", "map", "side", "type", "-id"}
+	input.Filters.SortSafeList = []string{
+		"id", "map", "side", "type", "title", "created_at",
+		"-id", "-map", "-side", "-type", "-title", "-created_at",
+	}
+	input.Filters.Cursor = app.readString(qs, "cursor", "")
 
-	v := validator.New()
-	v.Check(v.In(input.Filters.Sort, input.Filters.SortSafeList), "sort", "invalid sort value")
-	if !v.Valid() {
+	// пустой ?cursor= включает keyset пагинацию с первой записи
+	useCursor := qs.Has("cursor")
+	if useCursor && qs.Has("page") {
+		v.AddError("cursor", "must not be used together with page")
+	}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Erorrs)
 		return
 	}
 
-	grenades, err := app.models.Grenades.GetAll(input.Map, input.Side, input.Type, input.Filters)
+	publishedOnly := len(input.Statuses.Exclude) == 0 && len(input.Statuses.Include) == 1 && input.Statuses.Include[0] == "published"
+
+	if !publishedOnly {
+		user := app.contextGetUser(r)
+
+		permitted, err := app.hasPermission(r, data.PermissionModerationManage)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if !permitted && (user.IsAnonymous() || input.AuthorID != user.ID) {
+			app.notPermittedResponse(w, r)
+			return
+		}
+	}
+
+	var (
+		grenades []*data.Grenade
+		metadata data.Metadata
+		err      error
+	)
+
+	if useCursor {
+		grenades, metadata, err = app.models.Grenades.GetAllByCursor(input.GrenadeSearch, input.Filters)
+	} else {
+		grenades, metadata, err = app.models.Grenades.GetAll(input.GrenadeSearch, input.Filters)
+	}
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrInvalidFilterQuery):
+			v.AddError("filter", err.Error())
+			app.failedValidationResponse(w, r, v.Erorrs)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
@@ -255,14 +430,149 @@ func (app *application) getAllGrenadesHandler(w http.ResponseWriter, r *http.Req
 
 	wg.Wait()
 
-	cachePath := r.URL.Path + qs.Encode()
-	app.cache.Set(cachePath, envelope{"grenades": grenades}, 0)
+	ids := make([]int64, len(grenades))
+	for i := range grenades {
+		ids[i] = grenades[i].ID
+	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"grenades": grenades}, nil)
+	tags, err := app.models.Tags.GetByGrenadeIDs(ids)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
+
+	for _, grenade := range grenades {
+		grenade.Tags = tags[grenade.ID]
+	}
+
+	// кэш общий для всех пользователей, поэтому кэшируются только публичные выборки
+	if publishedOnly {
+		cachePath := r.URL.Path + qs.Encode()
+		app.cache.Set(cachePath, envelope{"grenades": grenades, "metadata": metadata}, 0)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"grenades": grenades, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// validateGrenadeTarget проверяет, что цель гранаты существует и на той же карте,
+// при ошибке сам пишет ответ и возвращает false
+func (app *application) validateGrenadeTarget(w http.ResponseWriter, r *http.Request, grenade *data.Grenade) bool {
+	if grenade.TargetID == 0 {
+		return true
+	}
+
+	v := validator.New()
+
+	target, err := app.models.Targets.Get(grenade.TargetID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("target_id", "target does not exist")
+			app.failedValidationResponse(w, r, v.Erorrs)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return false
+	}
+
+	if data.ValidateGrenadeTarget(grenade, target, v); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return false
+	}
+
+	return true
+}
+
+// requireGrenadeOwner пропускает автора гранаты или модератора,
+// при ошибке сам пишет ответ и возвращает false
+func (app *application) requireGrenadeOwner(w http.ResponseWriter, r *http.Request, grenade *data.Grenade) bool {
+	if grenade.CreatedBy != 0 && grenade.CreatedBy == app.contextGetUser(r).ID {
+		return true
+	}
+
+	permitted, err := app.hasPermission(r, data.PermissionModerationManage)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	if !permitted {
+		app.notPermittedResponse(w, r)
+		return false
+	}
+
+	return true
+}
+
+// requireGrenadeEditable - отправленные на проверку и опубликованные раскидки меняют только пользователи
+// с правом grenades:write, при ошибке сам пишет ответ и возвращает false
+func (app *application) requireGrenadeEditable(w http.ResponseWriter, r *http.Request, grenade *data.Grenade) bool {
+	if grenade.EditableByAuthor() {
+		return true
+	}
+
+	permitted, err := app.hasPermission(r, data.PermissionGrenadesWrite)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	if !permitted {
+		v := validator.New()
+		v.AddError("status", fmt.Sprintf("lineup is %s, only draft or rejected lineups can be edited", grenade.Status))
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return false
+	}
+
+	return true
+}
+
+// canViewUnpublishedGrenade - черновик, раскидку на проверке или отклоненную видят автор и модераторы
+func (app *application) canViewUnpublishedGrenade(r *http.Request, grenade *data.Grenade) (bool, error) {
+	user := app.contextGetUser(r)
+
+	if !user.IsAnonymous() && grenade.CreatedBy == user.ID {
+		return true, nil
+	}
+
+	return app.hasPermission(r, data.PermissionModerationManage)
+}
+
+// hideUnpublishedGrenades удаляет из grenades неопубликованные раскидки, которые пользователь запроса не может видеть
+func (app *application) hideUnpublishedGrenades(r *http.Request, grenades map[int64]*data.Grenade) error {
+	for id, grenade := range grenades {
+		if grenade.Status == "published" {
+			continue
+		}
+
+		visible, err := app.canViewUnpublishedGrenade(r, grenade)
+		if err != nil {
+			return err
+		}
+
+		if !visible {
+			delete(grenades, id)
+		}
+	}
+
+	return nil
+}
+
+// publishedGrenades оставляет только опубликованные раскидки
+func publishedGrenades(grenades []*data.Grenade) []*data.Grenade {
+	published := []*data.Grenade{}
+
+	for _, grenade := range grenades {
+		if grenade.Status == "published" {
+			published = append(published, grenade)
+		}
+	}
+
+	return published
 }
 
 func (app *application) createImagesURL(images []*data.Image) {
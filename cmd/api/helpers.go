@@ -9,8 +9,10 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
 )
 
 type envelope map[string]interface{}
@@ -129,3 +131,83 @@ func (app *application) readString(qs url.Values, key string, defaultValue strin
 	}
 	return q
 }
+
+func (app *application) readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
+	q := qs.Get(key)
+	if q == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(q)
+	if err != nil {
+		v.AddError(key, "must be an integer value")
+		return defaultValue
+	}
+
+	return i
+}
+
+// readTime читает время в формате RFC 3339, без параметра возвращает нулевое время
+func (app *application) readTime(qs url.Values, key string, v *validator.Validator) time.Time {
+	q := qs.Get(key)
+	if q == "" {
+		return time.Time{}
+	}
+
+	t, err := time.Parse(time.RFC3339, q)
+	if err != nil {
+		v.AddError(key, "must be an RFC 3339 timestamp")
+		return time.Time{}
+	}
+
+	return t
+}
+
+func (app *application) readIDs(qs url.Values, key string, v *validator.Validator) []int64 {
+	q := qs.Get(key)
+	if q == "" {
+		return []int64{}
+	}
+
+	ids := []int64{}
+	for _, value := range strings.Split(q, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || id < 1 {
+			v.AddError(key, "must be a comma-separated list of ids")
+			return []int64{}
+		}
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// hasPermission проверяет право code у пользователя запроса,
+// API ключ ограничен своими scopes, но не больше прав владельца
+func (app *application) hasPermission(r *http.Request, code string) (bool, error) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		return false, nil
+	}
+
+	if key := app.contextGetAPIKey(r); key != nil && !key.Scopes.Include(code) {
+		return false, nil
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return permissions.Include(code), nil
+}
+
+// roleRequiresTwoFactor - по настройке 2FA для роли обязательна
+func (app *application) roleRequiresTwoFactor(role string) bool {
+	for _, required := range app.config.twoFactor.requiredRoles {
+		if role == required {
+			return true
+		}
+	}
+	return false
+}
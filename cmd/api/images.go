@@ -58,6 +58,8 @@ func (app *application) uploadImageHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	app.audit(r, "create", "image", image.ID, nil, image)
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"image": image}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -82,7 +84,8 @@ func (app *application) deleteImageHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.models.Images.Delete(image.ID)
+	// изображение переносится в корзину, объект в S3 удалит очистка корзины
+	err = app.models.Images.Delete(image.ID, app.contextGetUser(r).ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -93,12 +96,7 @@ func (app *application) deleteImageHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// deleting from S3 storage
-	err = app.deleteImagesFromStorage([]*data.Image{image})
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
-		return
-	}
+	app.audit(r, "delete", "image", image.ID, image, nil)
 
 	err = app.writeJSON(w, http.StatusOK, envelope{"message": "image successfully deleted"}, nil)
 	if err != nil {
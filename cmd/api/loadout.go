@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/w3qxst1ck/cs2-grenades/internal/data"
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+func (app *application) getLoadoutHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	ids := app.readIDs(r.URL.Query(), "grenade_ids", v)
+	v.Check(len(ids) > 0, "grenade_ids", "must be provided")
+	v.Check(len(ids) <= 50, "grenade_ids", "must contain a maximum of 50 ids")
+	v.Check(validator.Unique(ids), "grenade_ids", "must not contain duplicate ids")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	grenades, err := app.models.Grenades.GetByIDs(ids)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.hideUnpublishedGrenades(r, grenades)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	items := make([]*data.LoadoutItem, 0, len(ids))
+	for _, id := range ids {
+		grenade, ok := grenades[id]
+		if !ok {
+			v.AddError("grenade_ids", "must contain only existing grenades")
+			app.failedValidationResponse(w, r, v.Erorrs)
+			return
+		}
+		items = append(items, &data.LoadoutItem{Grenade: grenade})
+	}
+
+	app.writeLoadout(w, r, items)
+}
+
+func (app *application) getExecuteLoadoutHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	execute, err := app.models.Executes.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	items := make([]*data.LoadoutItem, len(execute.Items))
+	for i, item := range execute.Items {
+		items[i] = &data.LoadoutItem{Grenade: item.Grenade, PlayerSlot: item.PlayerSlot}
+	}
+
+	app.writeLoadout(w, r, items)
+}
+
+func (app *application) writeLoadout(w http.ResponseWriter, r *http.Request, items []*data.LoadoutItem) {
+	prices, err := app.models.GrenadePrices.GetAll()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	loadout := data.PlanLoadout(items, prices)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"loadout": loadout}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"log"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -11,7 +12,9 @@ import (
 
 	"github.com/joho/godotenv"
 	"github.com/patrickmn/go-cache"
+	"github.com/w3qxst1ck/cs2-grenades/internal/auth"
 	"github.com/w3qxst1ck/cs2-grenades/internal/data"
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
 )
 
 const version = "1.0.0"
@@ -38,15 +41,33 @@ type config struct {
 	cors struct {
 		trustedOrigins []string
 	}
-	enableIP struct {
-		ip string
-	}
 	storageS3 struct {
 		URL string
 		Region string
 		Bucket string
 		DownloadUrl string
 	}
+	oidc struct {
+		name string
+		issuer string
+		clientID string
+		clientSecret string
+		redirectURL string
+	}
+	steam struct {
+		enabled bool
+		endpoint string
+		returnURL string
+		realm string
+	}
+	twoFactor struct {
+		issuer string
+		requiredRoles []string
+	}
+	trash struct {
+		retention time.Duration
+		purgeInterval time.Duration
+	}
 }
 
 type application struct {
@@ -55,6 +76,10 @@ type application struct {
 	models data.Models
 	wg     sync.WaitGroup
 	cache  *cache.Cache
+	oidc   *auth.OIDCProvider
+	steam  *auth.SteamProvider
+	// authFailures - неудачные попытки входа по IP
+	authFailures *clientLimiters
 }
 
 func main() {
@@ -106,18 +131,55 @@ func main() {
 	trustedOrigins := strings.Split(os.Getenv("TRUSTED_ORIGINS"), ",")
 	cfg.cors.trustedOrigins = trustedOrigins
 
-	// enable IP
-	enableIP := os.Getenv("ENABLE_API")
-	cfg.enableIP.ip = enableIP
-
 	// storage S3 selectel
 	cfg.storageS3.URL = os.Getenv("STORAGE_URL")
 	cfg.storageS3.Region = os.Getenv("STORAGE_REGION")
 	cfg.storageS3.Bucket = os.Getenv("STORAGE_BUCKET")
 	cfg.storageS3.DownloadUrl = os.Getenv("STORAGE_DOWNLOAD_URL")
 
+	// внешний вход, провайдер OIDC включается при заданном issuer
+	flag.StringVar(&cfg.oidc.name, "oidc-name", "oidc", "OIDC provider name used in /v1/auth/:provider")
+	flag.StringVar(&cfg.oidc.issuer, "oidc-issuer", os.Getenv("OIDC_ISSUER"), "OIDC issuer URL")
+	flag.StringVar(&cfg.oidc.clientID, "oidc-client-id", os.Getenv("OIDC_CLIENT_ID"), "OIDC client ID")
+	flag.StringVar(&cfg.oidc.clientSecret, "oidc-client-secret", os.Getenv("OIDC_CLIENT_SECRET"), "OIDC client secret")
+	flag.StringVar(&cfg.oidc.redirectURL, "oidc-redirect-url", os.Getenv("OIDC_REDIRECT_URL"), "OIDC callback URL")
+
+	// steam включается при заданном return url
+	flag.StringVar(&cfg.steam.endpoint, "steam-endpoint", auth.SteamOpenIDEndpoint, "Steam OpenID endpoint")
+	flag.StringVar(&cfg.steam.returnURL, "steam-return-url", os.Getenv("STEAM_RETURN_URL"), "Steam OpenID callback URL")
+	flag.StringVar(&cfg.steam.realm, "steam-realm", os.Getenv("STEAM_REALM"), "Steam OpenID realm")
+
+	// 2FA, для ролей из списка права доступны только после подключения TOTP
+	flag.StringVar(&cfg.twoFactor.issuer, "totp-issuer", "CS2 Grenades", "Issuer shown in authenticator apps")
+
+	twoFactorRoles := os.Getenv("TWO_FACTOR_REQUIRED_ROLES")
+	if twoFactorRoles == "" {
+		twoFactorRoles = "moderator,admin"
+	}
+	flag.StringVar(&twoFactorRoles, "two-factor-roles", twoFactorRoles, "Comma separated roles required to enable 2FA, empty to disable")
+
+	// корзина, после retention удаленные раскидки и изображения удаляются окончательно вместе с объектами в S3
+	flag.DurationVar(&cfg.trash.retention, "trash-retention", 30*24*time.Hour, "How long deleted items stay in the trash, 0 disables purging")
+	flag.DurationVar(&cfg.trash.purgeInterval, "trash-purge-interval", time.Hour, "How often the trash is purged")
+
 	flag.Parse()
 
+	for _, role := range strings.Split(twoFactorRoles, ",") {
+		role = strings.TrimSpace(role)
+		if role == "" {
+			continue
+		}
+
+		v := validator.New()
+		if data.ValidateRole(v, role); !v.Valid() {
+			logger.Fatalf("invalid role %q in two factor roles", role)
+		}
+
+		cfg.twoFactor.requiredRoles = append(cfg.twoFactor.requiredRoles, role)
+	}
+
+	cfg.steam.enabled = cfg.steam.returnURL != ""
+
 	db, err := openDB(cfg)
 	if err != nil {
 		logger.Fatal(err)
@@ -132,8 +194,31 @@ func main() {
 		logger: logger,
 		models: data.NewModels(db),
 		cache:  cache,
+		authFailures: newClientLimiters(),
 	}
 
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+
+	if cfg.oidc.issuer != "" {
+		app.oidc = auth.NewOIDCProvider(auth.OIDCConfig{
+			Name:         cfg.oidc.name,
+			Issuer:       cfg.oidc.issuer,
+			ClientID:     cfg.oidc.clientID,
+			ClientSecret: cfg.oidc.clientSecret,
+			RedirectURL:  cfg.oidc.redirectURL,
+		}, httpClient)
+	}
+
+	if cfg.steam.enabled {
+		app.steam = auth.NewSteamProvider(auth.SteamConfig{
+			Endpoint:  cfg.steam.endpoint,
+			ReturnURL: cfg.steam.returnURL,
+			Realm:     cfg.steam.realm,
+		}, httpClient)
+	}
+
+	app.startTrashPurge()
+
 	err = app.serve()
 	if err != nil {
 		logger.Print(err)
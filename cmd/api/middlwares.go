@@ -1,12 +1,18 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/w3qxst1ck/cs2-grenades/internal/data"
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
 	"golang.org/x/time/rate"
 )
 
@@ -23,30 +29,92 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 	})
 }
 
-func (app *application) rateLimit(next http.Handler) http.Handler {
-	type client struct {
-		limiter  *rate.Limiter
-		lastSeen time.Time
+// requestID берет id запроса из заголовка X-Request-ID, если его проставил прокси, или создает новый.
+// id возвращается в ответе и пишется в логи и журнал аудита
+func (app *application) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+
+		if !validRequestID(id) {
+			b := make([]byte, 16)
+			if _, err := rand.Read(b); err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+			id = hex.EncodeToString(b)
+		}
+
+		w.Header().Set("X-Request-ID", id)
+		r = app.contextSetRequestID(r, id)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validRequestID пропускает только короткие id из букв, цифр и "-_.:", чтобы в логи не попал произвольный текст
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:", c)) {
+			return false
+		}
 	}
 
-	var (
-		mu      sync.Mutex
-		clients = make(map[string]*client)
-	)
+	return true
+}
+
+// clientLimiters хранит лимитеры по ключу клиента, лимитеры неактивных клиентов удаляются
+type clientLimiters struct {
+	mu      sync.Mutex
+	clients map[string]*clientLimiter
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newClientLimiters() *clientLimiters {
+	l := &clientLimiters{clients: make(map[string]*clientLimiter)}
 
 	go func() {
 		for {
 			time.Sleep(time.Minute)
-			mu.Lock()
-			for ip, client := range clients {
+			l.mu.Lock()
+			for clientKey, client := range l.clients {
 				if time.Since(client.lastSeen) > time.Minute*3 {
-					delete(clients, ip)
+					delete(l.clients, clientKey)
 				}
 			}
-			mu.Unlock()
+			l.mu.Unlock()
 		}
 	}()
 
+	return l
+}
+
+// get возвращает лимитер клиента, новый создается с лимитами rps и burst
+func (l *clientLimiters) get(clientKey string, rps float64, burst int) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.clients[clientKey]; !ok {
+		l.clients[clientKey] = &clientLimiter{
+			limiter: rate.NewLimiter(rate.Limit(rps), burst),
+		}
+	}
+
+	l.clients[clientKey].lastSeen = time.Now()
+
+	return l.clients[clientKey].limiter
+}
+
+func (app *application) rateLimit(next http.Handler) http.Handler {
+	clients := newClientLimiters()
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if app.config.limiter.enabled {
 			ip, _, err := net.SplitHostPort(r.RemoteAddr)
@@ -55,22 +123,19 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 				return
 			}
 
-			mu.Lock()
-
-			if _, ok := clients[ip]; !ok {
-				clients[ip] = &client{
-					limiter: rate.NewLimiter(rate.Limit(app.config.limiter.rps), app.config.limiter.burst),
-				}
+			// запросы с API ключом лимитируются по ключу с лимитами его тарифа
+			clientKey := ip
+			rps, burst := app.config.limiter.rps, app.config.limiter.burst
+			if key := app.contextGetAPIKey(r); key != nil {
+				clientKey = fmt.Sprintf("key:%d", key.ID)
+				tier := data.APIKeyTiers[key.Tier]
+				rps, burst = tier.RPS, tier.Burst
 			}
 
-			clients[ip].lastSeen = time.Now()
-			if !clients[ip].limiter.Allow() {
-				mu.Unlock()
+			if !clients.get(clientKey, rps, burst).Allow() {
 				app.rateLimitExceededResponse(w, r)
 				return
 			}
-
-			mu.Unlock()
 		}
 
 		next.ServeHTTP(w, r)
@@ -125,19 +190,174 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 	})
 }
 
-func (app *application) enableIPs(next http.Handler) http.Handler {
+// authFailureRPS и authFailureBurst - сколько неудачных попыток входа по паролю, токену или API ключу
+// разрешено с одного IP. authenticate стоит до rateLimit, поэтому без этого лимита
+// перебор ключей не ограничен и каждая попытка идет в БД
+const (
+	authFailureRPS   = 0.2
+	authFailureBurst = 10
+)
+
+// limitAuthFailures проверяет, остались ли у IP запроса попытки входа, и возвращает failed,
+// который нужно вызвать при неверных учетных данных. false - попытки исчерпаны, ответ уже записан
+func (app *application) limitAuthFailures(w http.ResponseWriter, r *http.Request) (func(), bool) {
+	if !app.config.limiter.enabled {
+		return func() {}, true
+	}
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return nil, false
+	}
+
+	limiter := app.authFailures.get(ip, authFailureRPS, authFailureBurst)
+	if limiter.Tokens() < 1 {
+		app.rateLimitExceededResponse(w, r)
+		return nil, false
+	}
+
+	return func() { limiter.Allow() }, true
+}
+
+// authenticate кладет в контекст пользователя по токену из заголовка Authorization: Bearer <token>
+// или владельца ключа из X-API-Key, без заголовков пользователь анонимный
+func (app *application) authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		w.Header().Add("Vary", "Authorization")
+		w.Header().Add("Vary", "X-API-Key")
+
+		apiKey := r.Header.Get("X-API-Key")
+		authorizationHeader := r.Header.Get("Authorization")
+
+		if apiKey == "" && authorizationHeader == "" {
+			r = app.contextSetUser(r, data.AnonymousUser)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		failed, ok := app.limitAuthFailures(w, r)
+		if !ok {
+			return
+		}
+
+		if apiKey != "" {
+			app.authenticateAPIKey(w, r, apiKey, failed, next)
+			return
+		}
+
+		headerParts := strings.Split(authorizationHeader, " ")
+		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+			failed()
+			app.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		token := headerParts[1]
+
+		v := validator.New()
+
+		if data.ValidateTokenPlaintext(v, token); !v.Valid() {
+			failed()
+			app.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		user, err := app.models.Users.GetForToken(data.ScopeAuthentication, token)
 		if err != nil {
-			app.serverErrorResponse(w, r, err)
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				failed()
+				app.invalidAuthenticationTokenResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
 			return
 		}
 
-		if ip != app.config.enableIP.ip {
-			app.unavailableIPResponse(w, r)
+		r = app.contextSetUser(r, user)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) authenticateAPIKey(w http.ResponseWriter, r *http.Request, apiKey string, failed func(), next http.Handler) {
+	v := validator.New()
+
+	if data.ValidateAPIKeyPlaintext(v, apiKey); !v.Valid() {
+		failed()
+		app.invalidAPIKeyResponse(w, r)
+		return
+	}
+
+	key, user, err := app.models.APIKeys.Authenticate(apiKey)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			failed()
+			app.invalidAPIKeyResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	r = app.contextSetUser(r, user)
+	r = app.contextSetAPIKey(r, key)
+
+	next.ServeHTTP(w, r)
+}
+
+func (app *application) requireAuthenticatedUser(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+
+		if user.IsAnonymous() {
+			app.authenticationRequiredResponse(w, r)
 			return
 		}
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+// requireUserToken пропускает только пользователей, вошедших по токену, API ключом нельзя управлять ключами
+func (app *application) requireUserToken(next http.HandlerFunc) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if app.contextGetAPIKey(r) != nil {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return app.requireAuthenticatedUser(fn)
+}
+
+// requirePermission пропускает только пользователей, у роли которых есть право code
+// и подключена 2FA, если роль ее требует
+func (app *application) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		permitted, err := app.hasPermission(r, code)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if !permitted {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		// права ролей с обязательной 2FA доступны только после ее подключения
+		if user := app.contextGetUser(r); app.roleRequiresTwoFactor(user.Role) && !user.TwoFactorEnabled {
+			app.twoFactorEnrollmentRequiredResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return app.requireAuthenticatedUser(fn)
+}
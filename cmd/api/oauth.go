@@ -0,0 +1,237 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/w3qxst1ck/cs2-grenades/internal/auth"
+	"github.com/w3qxst1ck/cs2-grenades/internal/data"
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+// oauthStateTTL - сколько времени есть у пользователя на вход у провайдера
+const oauthStateTTL = 10 * time.Minute
+
+// startExternalLoginHandler возвращает адрес входа у провайдера.
+// Если запрос пришел с токеном, аккаунт провайдера будет привязан к текущему пользователю
+func (app *application) startExternalLoginHandler(w http.ResponseWriter, r *http.Request) {
+	provider := httprouter.ParamsFromContext(r.Context()).ByName("provider")
+
+	if !app.externalProviderEnabled(provider) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if app.contextGetAPIKey(r) != nil {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	state, err := app.models.OAuthStates.New(provider, user.ID, oauthStateTTL)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var authorizationURL string
+
+	switch provider {
+	case "steam":
+		authorizationURL = app.steam.AuthURL(state.Plaintext)
+	default:
+		authorizationURL, err = app.oidc.AuthCodeURL(r.Context(), state.Plaintext, state.Nonce)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"authorization_url": authorizationURL}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// externalLoginCallbackHandler - сюда провайдер возвращает пользователя после входа.
+// Пользователь ищется по привязанному аккаунту провайдера, без привязки создается новый
+func (app *application) externalLoginCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	provider := httprouter.ParamsFromContext(r.Context()).ByName("provider")
+
+	if !app.externalProviderEnabled(provider) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	qs := r.URL.Query()
+
+	// пользователь отказался от входа или провайдер вернул ошибку
+	if qs.Get("error") != "" {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	state, err := app.models.OAuthStates.Consume(provider, qs.Get("state"))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.badRequestResponse(w, r, errors.New("invalid or expired state"))
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var identity *auth.Identity
+
+	switch provider {
+	case "steam":
+		identity, err = app.steam.Verify(r.Context(), qs, state.Plaintext)
+	default:
+		identity, err = app.oidc.Exchange(r.Context(), qs.Get("code"), state.Nonce)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidIDToken), errors.Is(err, auth.ErrInvalidSteamResponse), errors.Is(err, auth.ErrProvider):
+			app.logError(r, err)
+			app.invalidCredentialsResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user, ok := app.externalLoginUser(w, r, identity, state.UserID)
+	if !ok {
+		return
+	}
+
+	env, err := app.newLoginTokens(r, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env["user"] = user
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// externalLoginUser находит, привязывает или создает пользователя для аккаунта провайдера,
+// при ошибке сам пишет ответ и возвращает false
+func (app *application) externalLoginUser(w http.ResponseWriter, r *http.Request, identity *auth.Identity, linkUserID int64) (*data.User, bool) {
+	v := validator.New()
+
+	user, err := app.models.UserIdentities.GetUser(identity.Provider, identity.Subject)
+	switch {
+	case err == nil:
+		if linkUserID != 0 && user.ID != linkUserID {
+			v.AddError("provider", "this account is already linked to another user")
+			app.failedValidationResponse(w, r, v.Erorrs)
+			return nil, false
+		}
+		return user, true
+	case !errors.Is(err, data.ErrRecordNotFound):
+		app.serverErrorResponse(w, r, err)
+		return nil, false
+	}
+
+	userIdentity := &data.UserIdentity{
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		UserID:   linkUserID,
+	}
+
+	if identity.EmailVerified {
+		userIdentity.Email = identity.Email
+	}
+
+	// привязка к пользователю, который начал вход с токеном
+	if linkUserID != 0 {
+		user, err = app.models.Users.Get(linkUserID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return nil, false
+		}
+
+		err = app.models.UserIdentities.Insert(userIdentity)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrDuplicateIdentity):
+				v.AddError("provider", "an account of this provider is already linked")
+				app.failedValidationResponse(w, r, v.Erorrs)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return nil, false
+		}
+
+		app.audit(r, "create", "user_identity", user.ID, nil, userIdentity)
+
+		return user, true
+	}
+
+	user = &data.User{
+		Name:  identity.Name,
+		Email: userIdentity.Email,
+	}
+	if user.Name == "" {
+		user.Name = fmt.Sprintf("%s_%s", identity.Provider, identity.Subject)
+	}
+	if len(user.Name) > 500 {
+		user.Name = user.Name[:500]
+	}
+
+	err = app.models.UserIdentities.InsertWithUser(user, userIdentity)
+	if err != nil {
+		switch {
+		// аккаунты с тем же email не связываются автоматически, иначе провайдер сможет войти в чужой аккаунт
+		case errors.Is(err, data.ErrDuplicateEmail):
+			v.AddError("email", "a user with this email address already exists, log in and link the account")
+			app.failedValidationResponse(w, r, v.Erorrs)
+		case errors.Is(err, data.ErrDuplicateIdentity):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	app.audit(r, "create", "user", user.ID, nil, user)
+	app.audit(r, "create", "user_identity", user.ID, nil, userIdentity)
+
+	return user, true
+}
+
+func (app *application) externalProviderEnabled(provider string) bool {
+	switch {
+	case provider == "steam":
+		return app.steam != nil
+	case app.oidc != nil:
+		return provider == app.oidc.Name()
+	default:
+		return false
+	}
+}
+
+func (app *application) getCurrentUserIdentitiesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	identities, err := app.models.UserIdentities.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"identities": identities}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/w3qxst1ck/cs2-grenades/internal/data"
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+func (app *application) createRelationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		ToGrenadeID int64  `json:"to_grenade_id"`
+		Kind        string `json:"kind"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	grenades, err := app.models.Grenades.GetByIDs([]int64{id, input.ToGrenadeID})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	from, ok := grenades[id]
+	if !ok {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	to, ok := grenades[input.ToGrenadeID]
+	if !ok {
+		v.AddError("to_grenade_id", "grenade does not exist")
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	relation := &data.GrenadeRelation{
+		FromGrenadeID: from.ID,
+		ToGrenadeID:   to.ID,
+		Kind:          input.Kind,
+	}
+
+	if data.ValidateRelation(relation, from, to, v); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	err = app.models.Relations.Insert(relation)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateRelation):
+			v.AddError("to_grenade_id", "this relation already exists")
+			app.failedValidationResponse(w, r, v.Erorrs)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.clearRelationCache(from.Map, from.ID, to.ID)
+	app.audit(r, "create", "relation", relation.ID, nil, relation)
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/relations/%d", relation.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"relation": relation}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getRelationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	relation, err := app.models.Relations.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"relation": relation}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteRelationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	relation, err := app.models.Relations.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Relations.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// карта связи берется у раскидок, раскидки из корзины GetByIDs пропускает
+	grenades, err := app.models.Grenades.GetByIDs([]int64{relation.FromGrenadeID, relation.ToGrenadeID})
+	if err != nil {
+		app.logError(r, err)
+	}
+	for _, grenade := range grenades {
+		app.clearRelationCache(grenade.Map, relation.FromGrenadeID, relation.ToGrenadeID)
+	}
+
+	app.audit(r, "delete", "relation", relation.ID, relation, nil)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "relation successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getMapGraphHandler(w http.ResponseWriter, r *http.Request) {
+	csMap := httprouter.ParamsFromContext(r.Context()).ByName("map")
+
+	graph, err := app.models.Relations.GetGraph(csMap)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.cache.Set(r.URL.Path, envelope{"graph": graph}, 0)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"graph": graph}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// clearRelationCache удаляет из кэша граф карты и раскидки связи, в ответ раскидки встроены ее связи
+func (app *application) clearRelationCache(csMap string, grenadeIDs ...int64) {
+	app.cache.Delete(fmt.Sprintf("/v1/maps/%s/graph", csMap))
+
+	for _, id := range grenadeIDs {
+		app.cache.Delete(fmt.Sprintf("/v1/grenades/%d", id))
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/w3qxst1ck/cs2-grenades/internal/data"
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+// createReportHandler - жалоба игрока на сломанную раскидку
+func (app *application) createReportHandler(w http.ResponseWriter, r *http.Request) {
+	// на чужие черновики жаловаться нельзя, и ответ не должен выдавать, что они существуют
+	grenade, ok := app.readVisibleGrenade(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+		Build  string `json:"build"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	report := &data.Report{
+		GrenadeID: grenade.ID,
+		Reason:    input.Reason,
+		Build:     input.Build,
+	}
+
+	v := validator.New()
+	if data.ValidateReport(report, v); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	err = app.models.Reports.Insert(report)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.audit(r, "create", "report", report.ID, nil, report)
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/reports/%d", report.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"report": report}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getAllReportsHandler - очередь жалоб для модераторов, по умолчанию только открытые
+func (app *application) getAllReportsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Status    string
+		GrenadeID int
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+	input.Status = app.readString(qs, "status", "open")
+	input.GrenadeID = app.readInt(qs, "grenade_id", 0, v)
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "created_at")
+	input.Filters.SortSafeList = []string{"id", "created_at", "-id", "-created_at"}
+
+	v.Check(input.Status == "" || v.In(input.Status, data.ReportStatuses), "status", "value of status must be open|resolved|confirmed")
+	v.Check(input.GrenadeID >= 0, "grenade_id", "must be a positive integer")
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	reports, metadata, err := app.models.Reports.GetAll(input.Status, int64(input.GrenadeID), input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	ids := make([]int64, len(reports))
+	for i, report := range reports {
+		ids[i] = report.GrenadeID
+	}
+
+	// список жалоб доступен только модераторам, им видны раскидки в любом статусе
+	grenades, err := app.models.Grenades.GetByIDs(ids)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for _, report := range reports {
+		report.Grenade = grenades[report.GrenadeID]
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"reports": reports, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getReportHandler(w http.ResponseWriter, r *http.Request) {
+	report, ok := app.readReport(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"report": report}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// resolveReportHandler закрывает жалобу, раскидка остается без изменений
+func (app *application) resolveReportHandler(w http.ResponseWriter, r *http.Request) {
+	app.changeReportStatus(w, r, app.models.Reports.Resolve)
+}
+
+// markReportBrokenHandler подтверждает жалобу и отмечает раскидку сломанной
+func (app *application) markReportBrokenHandler(w http.ResponseWriter, r *http.Request) {
+	editorID := app.contextGetUser(r).ID
+
+	app.changeReportStatus(w, r, func(report *data.Report) error {
+		return app.models.Reports.MarkBroken(report, editorID)
+	})
+}
+
+func (app *application) changeReportStatus(w http.ResponseWriter, r *http.Request, change func(*data.Report) error) {
+	report, ok := app.readReport(w, r)
+	if !ok {
+		return
+	}
+
+	v := validator.New()
+	if v.Check(report.Status == "open", "status", "report is already closed"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	before := auditSnapshot(report)
+	grenadeBefore := auditSnapshot(report.Grenade)
+
+	var grenadeVersion int32
+	if report.Grenade != nil {
+		grenadeVersion = report.Grenade.Version
+	}
+
+	err := change(report)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// статус раскидки мог измениться
+	report.Grenade, err = app.models.Grenades.Get(report.GrenadeID)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.audit(r, "update", "report", report.ID, before, report)
+
+	// MarkBroken меняет статус проверки раскидки
+	if report.Grenade != nil && report.Grenade.Version != grenadeVersion {
+		app.clearGrenadeCache(report.Grenade.Map)
+		app.audit(r, "update", "grenade", report.GrenadeID, grenadeBefore, report.Grenade)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"report": report}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readReport достает жалобу по id из url вместе с раскидкой, при ошибке сам пишет ответ
+func (app *application) readReport(w http.ResponseWriter, r *http.Request) (*data.Report, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	report, err := app.models.Reports.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	report.Grenade, err = app.models.Grenades.Get(report.GrenadeID)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return nil, false
+	}
+
+	return report, true
+}
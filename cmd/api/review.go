@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/w3qxst1ck/cs2-grenades/internal/data"
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+// submitGrenadeHandler отправляет черновик или отклоненную раскидку автора на проверку
+func (app *application) submitGrenadeHandler(w http.ResponseWriter, r *http.Request) {
+	grenade, ok := app.readGrenade(w, r)
+	if !ok {
+		return
+	}
+
+	if !app.requireGrenadeOwner(w, r, grenade) {
+		return
+	}
+
+	app.changeGrenadeStatus(w, r, grenade, "pending_review", "")
+}
+
+// approveGrenadeHandler публикует раскидку, комментарий модератора необязателен
+func (app *application) approveGrenadeHandler(w http.ResponseWriter, r *http.Request) {
+	app.reviewGrenade(w, r, "published")
+}
+
+// rejectGrenadeHandler отклоняет раскидку на проверке или снимает с публикации, нужен комментарий для автора
+func (app *application) rejectGrenadeHandler(w http.ResponseWriter, r *http.Request) {
+	app.reviewGrenade(w, r, "rejected")
+}
+
+func (app *application) reviewGrenade(w http.ResponseWriter, r *http.Request, status string) {
+	var input struct {
+		Comment string `json:"comment"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	grenade, ok := app.readGrenade(w, r)
+	if !ok {
+		return
+	}
+
+	app.changeGrenadeStatus(w, r, grenade, status, input.Comment)
+}
+
+func (app *application) changeGrenadeStatus(w http.ResponseWriter, r *http.Request, grenade *data.Grenade, status string, comment string) {
+	v := validator.New()
+
+	if data.ValidateStatusTransition(v, grenade, status, comment); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	before := auditSnapshot(grenade)
+
+	err := app.models.Grenades.SetStatus(grenade, status, comment, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// снятая с публикации раскидка не должна отдаваться из кэша, в том числе в списках
+	app.clearGrenadeCache(grenade.Map)
+	app.audit(r, "update", "grenade", grenade.ID, before, grenade)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"grenade": grenade}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readGrenade достает гранату по id из url, при ошибке сам пишет ответ
+func (app *application) readGrenade(w http.ResponseWriter, r *http.Request) (*data.Grenade, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	grenade, err := app.models.Grenades.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return grenade, true
+}
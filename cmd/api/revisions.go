@@ -0,0 +1,232 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/w3qxst1ck/cs2-grenades/internal/data"
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+func (app *application) getGrenadeRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	grenade, ok := app.readVisibleGrenade(w, r)
+	if !ok {
+		return
+	}
+
+	var filters data.Filters
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+	filters.Page = app.readInt(qs, "page", 1, v)
+	filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	filters.Sort = app.readString(qs, "sort", "-version")
+	filters.SortSafeList = []string{"version", "-version"}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	revisions, metadata, err := app.models.GrenadeRevisions.GetAll(grenade.ID, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"revisions": revisions, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getGrenadeRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	grenade, ok := app.readVisibleGrenade(w, r)
+	if !ok {
+		return
+	}
+
+	revision, ok := app.readRevision(w, r, grenade)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"revision": revision}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getGrenadeDiffHandler сравнивает версии ?from и ?to, по умолчанию текущую с предыдущей
+func (app *application) getGrenadeDiffHandler(w http.ResponseWriter, r *http.Request) {
+	grenade, ok := app.readVisibleGrenade(w, r)
+	if !ok {
+		return
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+	to := app.readInt(qs, "to", int(grenade.Version), v)
+	from := app.readInt(qs, "from", to-1, v)
+
+	v.Check(from > 0, "from", "must be a positive integer")
+	v.Check(to > 0, "to", "must be a positive integer")
+	v.Check(from != to, "from", "must differ from to")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	revisions := make([]*data.GrenadeRevision, 2)
+
+	for i, version := range []int{from, to} {
+		revision, err := app.models.GrenadeRevisions.Get(grenade.ID, int32(version))
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+		revisions[i] = revision
+	}
+
+	changes, err := data.DiffRevisions(revisions[0], revisions[1])
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{
+		"grenade_id": grenade.ID,
+		"from":       from,
+		"to":         to,
+		"changes":    changes,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// rollbackGrenadeHandler возвращает содержимое раскидки из ревизии, откат сохраняется новой версией
+func (app *application) rollbackGrenadeHandler(w http.ResponseWriter, r *http.Request) {
+	grenade, ok := app.readGrenade(w, r)
+	if !ok {
+		return
+	}
+
+	if !app.requireGrenadeOwner(w, r, grenade) || !app.requireGrenadeEditable(w, r, grenade) {
+		return
+	}
+
+	revision, ok := app.readRevision(w, r, grenade)
+	if !ok {
+		return
+	}
+
+	v := validator.New()
+
+	if v.Check(revision.Version != grenade.Version, "version", "is the current version"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	before := auditSnapshot(grenade)
+
+	tags, err := revision.RestoreContent(grenade)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	grenade.UpdatedBy = app.contextGetUser(r).ID
+
+	// цель могли удалить после ревизии
+	if data.ValidateGrenade(grenade, v); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	if !app.validateGrenadeTarget(w, r, grenade) {
+		return
+	}
+
+	err = app.models.Grenades.Update(grenade, fmt.Sprintf("rollback to version %d", revision.Version), tags)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		// тег могли удалить или переименовать после ревизии
+		case errors.Is(err, data.ErrUnknownTag):
+			v.AddError("tags", "revision contains tags that no longer exist")
+			app.failedValidationResponse(w, r, v.Erorrs)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.cache.Delete(fmt.Sprintf("/v1/grenades/%d", grenade.ID))
+	app.audit(r, "update", "grenade", grenade.ID, before, grenade)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"grenade": grenade}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readVisibleGrenade достает гранату по id из url, неопубликованную - только для автора и модераторов,
+// при ошибке сам пишет ответ
+func (app *application) readVisibleGrenade(w http.ResponseWriter, r *http.Request) (*data.Grenade, bool) {
+	grenade, ok := app.readGrenade(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	if grenade.Status != "published" {
+		visible, err := app.canViewUnpublishedGrenade(r, grenade)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return nil, false
+		}
+
+		if !visible {
+			app.notFoundResponse(w, r)
+			return nil, false
+		}
+	}
+
+	return grenade, true
+}
+
+// readRevision достает ревизию гранаты по :version из url, при ошибке сам пишет ответ
+func (app *application) readRevision(w http.ResponseWriter, r *http.Request, grenade *data.Grenade) (*data.GrenadeRevision, bool) {
+	version, err := strconv.ParseInt(httprouter.ParamsFromContext(r.Context()).ByName("version"), 10, 32)
+	if err != nil || version < 1 {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	revision, err := app.models.GrenadeRevisions.Get(grenade.ID, int32(version))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return revision, true
+}
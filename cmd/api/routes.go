@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/w3qxst1ck/cs2-grenades/internal/data"
 )
 
 func (app *application) routes() http.Handler {
@@ -18,12 +19,89 @@ func (app *application) routes() http.Handler {
 
 	router.HandlerFunc(http.MethodGet, "/v1/grenades/", app.checkCache(app.getAllGrenadesHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/grenades/:id", app.checkCache(app.getGrenadeHandler))
-	router.HandlerFunc(http.MethodPost, "/v1/grenades", app.createGrenadeHandler)
-	router.HandlerFunc(http.MethodPatch, "/v1/grenades/:id", app.updateGrenadeHandler)
-	router.HandlerFunc(http.MethodDelete, "/v1/grenades/:id", app.deleteGrenadeHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/grenades", app.requirePermission(data.PermissionGrenadesSubmit, app.createGrenadeHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/grenades/:id", app.requirePermission(data.PermissionGrenadesSubmit, app.updateGrenadeHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/grenades/:id", app.requirePermission(data.PermissionGrenadesSubmit, app.deleteGrenadeHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/grenades/:id/submit", app.requirePermission(data.PermissionGrenadesSubmit, app.submitGrenadeHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/grenades/:id/approve", app.requirePermission(data.PermissionModerationManage, app.approveGrenadeHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/grenades/:id/reject", app.requirePermission(data.PermissionModerationManage, app.rejectGrenadeHandler))
 
-	router.HandlerFunc(http.MethodPost, "/v1/grenades/:id/images", app.uploadImageHandler)
-	router.HandlerFunc(http.MethodDelete, "/v1/images/:id", app.deleteImageHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/grenades/:id/revisions", app.getGrenadeRevisionsHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/grenades/:id/revisions/:version", app.getGrenadeRevisionHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/grenades/:id/revisions/:version/rollback", app.requirePermission(data.PermissionGrenadesSubmit, app.rollbackGrenadeHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/grenades/:id/diff", app.getGrenadeDiffHandler)
 
-	return app.recoverPanic(app.enableIPs(app.rateLimit((router))))
+	router.HandlerFunc(http.MethodPost, "/v1/grenades/:id/relations", app.requirePermission(data.PermissionGrenadesWrite, app.createRelationHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/relations/:id", app.getRelationHandler)
+	router.HandlerFunc(http.MethodDelete, "/v1/relations/:id", app.requirePermission(data.PermissionGrenadesWrite, app.deleteRelationHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/maps/:map/graph", app.checkCache(app.getMapGraphHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/maps/:map/updated", app.requirePermission(data.PermissionModerationManage, app.mapUpdatedHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/grenades/:id/verification", app.requirePermission(data.PermissionGrenadesWrite, app.setGrenadeVerificationHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/grenades/:id/reports", app.createReportHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/reports", app.requirePermission(data.PermissionModerationManage, app.getAllReportsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/reports/:id", app.requirePermission(data.PermissionModerationManage, app.getReportHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/reports/:id/resolve", app.requirePermission(data.PermissionModerationManage, app.resolveReportHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/reports/:id/mark-broken", app.requirePermission(data.PermissionModerationManage, app.markReportBrokenHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/tags", app.getAllTagsHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/tags/:id", app.getTagHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/tags", app.requirePermission(data.PermissionGrenadesWrite, app.createTagHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/tags/:id", app.requirePermission(data.PermissionGrenadesWrite, app.updateTagHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/tags/:id", app.requirePermission(data.PermissionGrenadesWrite, app.deleteTagHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/targets", app.getAllTargetsHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/targets/:id", app.getTargetHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/targets", app.requirePermission(data.PermissionGrenadesWrite, app.createTargetHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/targets/:id", app.requirePermission(data.PermissionGrenadesWrite, app.updateTargetHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/targets/:id", app.requirePermission(data.PermissionGrenadesWrite, app.deleteTargetHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/executes", app.getAllExecutesHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/executes/:id", app.getExecuteHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/executes/:id/timeline", app.getExecuteTimelineHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/executes/:id/loadout", app.getExecuteLoadoutHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/executes", app.requirePermission(data.PermissionGrenadesWrite, app.createExecuteHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/executes/:id", app.requirePermission(data.PermissionGrenadesWrite, app.updateExecuteHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/executes/:id", app.requirePermission(data.PermissionGrenadesWrite, app.deleteExecuteHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/loadout", app.getLoadoutHandler)
+
+	router.HandlerFunc(http.MethodGet, "/v1/search/suggest", app.checkCache(app.suggestHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/callouts", app.getAllCalloutsHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/callouts", app.requirePermission(data.PermissionGrenadesWrite, app.createCalloutHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/callouts/:id", app.requirePermission(data.PermissionGrenadesWrite, app.deleteCalloutHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/trash/grenades", app.requirePermission(data.PermissionModerationManage, app.getGrenadesTrashHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/trash/grenades/:id/restore", app.requirePermission(data.PermissionModerationManage, app.restoreGrenadeHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/trash/images", app.requirePermission(data.PermissionModerationManage, app.getImagesTrashHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/trash/images/:id/restore", app.requirePermission(data.PermissionModerationManage, app.restoreImageHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/grenades/:id/images", app.requirePermission(data.PermissionImagesWrite, app.uploadImageHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/images/:id", app.requirePermission(data.PermissionImagesWrite, app.deleteImageHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/users/me", app.requireAuthenticatedUser(app.getCurrentUserHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/authentication", app.createAuthenticationTokenHandler)
+	router.HandlerFunc(http.MethodDelete, "/v1/tokens/authentication", app.requireUserToken(app.deleteAuthenticationTokenHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/two-factor", app.createTwoFactorAuthenticationTokenHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/2fa", app.requireUserToken(app.getTwoFactorHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/2fa", app.requireUserToken(app.enableTwoFactorHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/2fa/confirm", app.requireUserToken(app.confirmTwoFactorHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/2fa", app.requireUserToken(app.disableTwoFactorHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/2fa/recovery-codes", app.requireUserToken(app.regenerateRecoveryCodesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/identities", app.requireAuthenticatedUser(app.getCurrentUserIdentitiesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/auth/:provider/login", app.startExternalLoginHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/auth/:provider/callback", app.externalLoginCallbackHandler)
+	router.HandlerFunc(http.MethodPut, "/v1/users/:id/role", app.requirePermission(data.PermissionUsersManage, app.updateUserRoleHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/audit", app.requirePermission(data.PermissionUsersManage, app.getAuditLogHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/audit/export", app.requirePermission(data.PermissionUsersManage, app.exportAuditLogHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/api-keys", app.requireUserToken(app.getAllAPIKeysHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/api-keys", app.requireUserToken(app.createAPIKeyHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/api-keys/:id", app.requireUserToken(app.revokeAPIKeyHandler))
+
+	// rateLimit после authenticate, чтобы лимитировать API ключи по их тарифу,
+	// неудачные попытки входа authenticate лимитирует по IP сам
+	return app.requestID(app.recoverPanic(app.authenticate(app.rateLimit(router))))
 }
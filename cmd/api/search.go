@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/w3qxst1ck/cs2-grenades/internal/data"
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+func (app *application) suggestHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	q := app.readString(qs, "q", "")
+
+	v := validator.New()
+	if data.ValidateSuggestQuery(q, v); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	suggestions, err := app.models.Search.Suggest(q, 5)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	cachePath := r.URL.Path + qs.Encode()
+	app.cache.Set(cachePath, envelope{"suggestions": suggestions}, 0)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"suggestions": suggestions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/w3qxst1ck/cs2-grenades/internal/data"
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+const (
+	// authenticationTokenTTL - время жизни токена после входа
+	authenticationTokenTTL = 24 * time.Hour
+	// twoFactorTokenTTL - сколько времени есть на ввод кода 2FA после пароля
+	twoFactorTokenTTL = 5 * time.Minute
+)
+
+func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	data.ValidateEmail(v, input.Email)
+	data.ValidatePasswordPlaintext(v, input.Password)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	// перебор паролей ограничен так же, как перебор токенов
+	failed, ok := app.limitAuthFailures(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := app.models.Users.GetByEmail(input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			failed()
+			app.invalidCredentialsResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	match, err := user.Password.Matches(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !match {
+		failed()
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	env, err := app.newLoginTokens(r, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// newLoginTokens выдает токен authentication после входа, а пользователям с 2FA - токен two-factor,
+// который меняется на токен authentication через /v1/tokens/two-factor
+func (app *application) newLoginTokens(r *http.Request, user *data.User) (envelope, error) {
+	if user.TwoFactorEnabled {
+		token, err := app.models.Tokens.New(user.ID, twoFactorTokenTTL, data.ScopeTwoFactor)
+		if err != nil {
+			return nil, err
+		}
+
+		app.auditToken(r, "create", token)
+
+		return envelope{"two_factor_token": token}, nil
+	}
+
+	token, err := app.models.Tokens.New(user.ID, authenticationTokenTTL, data.ScopeAuthentication)
+	if err != nil {
+		return nil, err
+	}
+
+	app.auditToken(r, "create", token)
+
+	return envelope{"authentication_token": token}, nil
+}
+
+// auditToken пишет выдачу или отзыв токена в журнал без самого токена,
+// у токенов нет id, поэтому entity_id - id пользователя
+func (app *application) auditToken(r *http.Request, action string, token *data.Token) {
+	snapshot := envelope{"scope": token.Scope, "expiry": token.Expiry}
+
+	switch action {
+	case "delete":
+		app.audit(r, action, "token", token.UserID, snapshot, nil)
+	default:
+		app.audit(r, action, "token", token.UserID, nil, snapshot)
+	}
+}
+
+// createTwoFactorAuthenticationTokenHandler меняет токен two-factor и код TOTP или код восстановления на токен authentication
+func (app *application) createTwoFactorAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		TwoFactorToken string `json:"two_factor_token"`
+		Code           string `json:"code"`
+		RecoveryCode   string `json:"recovery_code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	data.ValidateTokenPlaintext(v, input.TwoFactorToken)
+	v.Check(input.Code != "" || input.RecoveryCode != "", "code", "code or recovery_code must be provided")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	user, err := app.models.Users.GetForToken(data.ScopeTwoFactor, input.TwoFactorToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidCredentialsResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// токен two-factor одноразовый, после неверного кода нужно снова войти с паролем,
+	// так коды нельзя перебирать с одним токеном
+	err = app.models.Tokens.Delete(data.ScopeTwoFactor, input.TwoFactorToken)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	match, err := app.verifySecondFactor(user, input.Code, input.RecoveryCode)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !match {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	token, err := app.models.Tokens.New(user.ID, authenticationTokenTTL, data.ScopeAuthentication)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.auditToken(r, "create", token)
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteAuthenticationTokenHandler отзывает токен из заголовка Authorization
+func (app *application) deleteAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	err := app.models.Tokens.Delete(data.ScopeAuthentication, token)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.auditToken(r, "delete", &data.Token{UserID: app.contextGetUser(r).ID, Scope: data.ScopeAuthentication})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "token successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
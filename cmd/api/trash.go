@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/w3qxst1ck/cs2-grenades/internal/data"
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+// trashPurgeBatch - сколько раскидок и изображений удаляется за один проход очистки
+const trashPurgeBatch = 100
+
+func (app *application) getGrenadesTrashHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.GrenadeSearch
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+	input.Deleted = true
+	input.Maps = data.ParseListFilter(app.readString(qs, "map", ""))
+	input.AuthorID = int64(app.readInt(qs, "author", 0, v))
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-deleted_at")
+	input.Filters.SortSafeList = []string{"id", "deleted_at", "-id", "-deleted_at"}
+
+	data.ValidateListFilter(v, "map", input.Maps, nil)
+	v.Check(input.AuthorID >= 0, "author", "must be a positive integer")
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	grenades, metadata, err := app.models.Grenades.GetAll(input.GrenadeSearch, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"grenades": grenades, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) restoreGrenadeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	grenade, err := app.models.Grenades.GetDeleted(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	before := auditSnapshot(grenade)
+
+	err = app.models.Grenades.Restore(grenade, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.clearGrenadeCache(grenade.Map)
+	app.audit(r, "restore", "grenade", grenade.ID, before, grenade)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"grenade": grenade}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getImagesTrashHandler(w http.ResponseWriter, r *http.Request) {
+	var filters data.Filters
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+	filters.Page = app.readInt(qs, "page", 1, v)
+	filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	filters.Sort = "-deleted_at"
+	filters.SortSafeList = []string{"-deleted_at"}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	images, metadata, err := app.models.Images.GetTrash(filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.createImagesURL(images)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"images": images, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) restoreImageHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	image, err := app.models.Images.GetDeleted(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// изображение удаленной раскидки вернется вместе с ней
+	_, err = app.models.Grenades.Get(image.GrenadeID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v := validator.New()
+			v.AddError("grenade_id", "grenade is in the trash, restore it first")
+			app.failedValidationResponse(w, r, v.Erorrs)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	before := auditSnapshot(image)
+
+	err = app.models.Images.Restore(image.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	image.DeletedAt = nil
+	image.DeletedBy = 0
+	app.createImagesURL([]*data.Image{image})
+
+	app.audit(r, "restore", "image", image.ID, before, image)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"image": image}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// startTrashPurge запускает периодическую очистку корзины, при retention 0 корзина не очищается
+func (app *application) startTrashPurge() {
+	if app.config.trash.retention <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(app.config.trash.purgeInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			app.purgeTrash()
+		}
+	}()
+}
+
+// purgeTrash окончательно удаляет записи старше retention. Сначала удаляются объекты в хранилище,
+// при ошибке записи остаются и удаляются на следующем проходе
+func (app *application) purgeTrash() {
+	// при остановке сервера дожидаемся текущего прохода
+	app.wg.Add(1)
+	defer app.wg.Done()
+
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.Print(fmt.Errorf("trash purge: %s", err))
+		}
+	}()
+
+	before := time.Now().Add(-app.config.trash.retention)
+
+	grenadeIDs, err := app.models.Grenades.GetDeletedBefore(before, trashPurgeBatch)
+	if err != nil {
+		app.logger.Print(fmt.Errorf("trash purge: %w", err))
+		return
+	}
+
+	images, err := app.models.Images.GetForPurge(grenadeIDs, before, trashPurgeBatch)
+	if err != nil {
+		app.logger.Print(fmt.Errorf("trash purge: %w", err))
+		return
+	}
+
+	if len(grenadeIDs) == 0 && len(images) == 0 {
+		return
+	}
+
+	err = app.deleteImagesFromStorage(images)
+	if err != nil {
+		app.logger.Print(fmt.Errorf("trash purge: %w", err))
+		return
+	}
+
+	imageIDs := make([]int64, len(images))
+	for i := range images {
+		imageIDs[i] = images[i].ID
+	}
+
+	purgedImages, err := app.models.Images.Purge(imageIDs)
+	if err != nil {
+		app.logger.Print(fmt.Errorf("trash purge: %w", err))
+		return
+	}
+
+	for _, image := range images {
+		app.audit(nil, "purge", "image", image.ID, image, nil)
+	}
+
+	purgedGrenades, err := app.models.Grenades.Purge(grenadeIDs)
+	if err != nil {
+		app.logger.Print(fmt.Errorf("trash purge: %w", err))
+		return
+	}
+
+	for _, id := range grenadeIDs {
+		app.audit(nil, "purge", "grenade", id, nil, nil)
+	}
+
+	app.logger.Printf("trash purge: deleted %d grenades and %d images", purgedGrenades, purgedImages)
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/w3qxst1ck/cs2-grenades/internal/auth"
+	"github.com/w3qxst1ck/cs2-grenades/internal/data"
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+// verifySecondFactor проверяет код TOTP или одноразовый код восстановления
+func (app *application) verifySecondFactor(user *data.User, code, recoveryCode string) (bool, error) {
+	switch {
+	case code != "":
+		step, ok := auth.ValidateTOTP(user.TOTPSecret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		return app.models.Users.UseTOTPStep(user.ID, step)
+	case recoveryCode != "":
+		return app.models.RecoveryCodes.Use(user.ID, recoveryCode)
+	default:
+		return false, nil
+	}
+}
+
+// updateTwoFactorUser сохраняет настройки 2FA пользователя, при ошибке сам пишет ответ и возвращает false
+func (app *application) updateTwoFactorUser(w http.ResponseWriter, r *http.Request, user *data.User) bool {
+	err := app.models.Users.Update(user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return false
+	}
+
+	return true
+}
+
+// enableTwoFactorHandler создает секрет TOTP, 2FA включится после подтверждения кодом.
+// Повторный запрос до подтверждения заменяет секрет
+func (app *application) enableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.TwoFactorEnabled {
+		v := validator.New()
+		v.AddError("two_factor", "is already enabled")
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	user.TOTPSecret = secret
+
+	if !app.updateTwoFactorUser(w, r, user) {
+		return
+	}
+
+	// секрет и коды восстановления в журнал не пишутся, у 2FA нет id, поэтому entity_id - id пользователя
+	app.audit(r, "create", "two_factor", user.ID, nil, envelope{"enabled": false})
+
+	account := user.Email
+	if account == "" {
+		account = user.Name
+	}
+
+	env := envelope{
+		"secret":           secret,
+		"provisioning_uri": auth.TOTPProvisioningURI(app.config.twoFactor.issuer, account, secret),
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// confirmTwoFactorHandler включает 2FA по первому коду из приложения и выдает коды восстановления
+func (app *application) confirmTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code string `json:"code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	v := validator.New()
+
+	v.Check(input.Code != "", "code", "must be provided")
+	v.Check(!user.TwoFactorEnabled, "two_factor", "is already enabled")
+	v.Check(user.TOTPSecret != "", "two_factor", "enrollment must be started first")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	match, err := app.verifySecondFactor(user, input.Code, "")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !match {
+		v.AddError("code", "is invalid or expired")
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	user.TwoFactorEnabled = true
+
+	if !app.updateTwoFactorUser(w, r, user) {
+		return
+	}
+
+	codes, err := app.models.RecoveryCodes.Replace(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.audit(r, "update", "two_factor", user.ID, envelope{"enabled": false}, envelope{"enabled": true, "recovery_codes": len(codes)})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user, "recovery_codes": codes}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// disableTwoFactorHandler отключает 2FA, если роль ее не требует
+func (app *application) disableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	v := validator.New()
+
+	v.Check(input.Code != "" || input.RecoveryCode != "", "code", "code or recovery_code must be provided")
+	v.Check(user.TwoFactorEnabled, "two_factor", "is not enabled")
+	v.Check(!app.roleRequiresTwoFactor(user.Role), "two_factor", "is required for your role")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	match, err := app.verifySecondFactor(user, input.Code, input.RecoveryCode)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !match {
+		v.AddError("code", "is invalid or expired")
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	user.TwoFactorEnabled = false
+	user.TOTPSecret = ""
+
+	if !app.updateTwoFactorUser(w, r, user) {
+		return
+	}
+
+	err = app.models.RecoveryCodes.DeleteAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.audit(r, "delete", "two_factor", user.ID, envelope{"enabled": true}, nil)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "two-factor authentication successfully disabled"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// regenerateRecoveryCodesHandler заменяет коды восстановления, старые коды перестают работать
+func (app *application) regenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code string `json:"code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	v := validator.New()
+
+	v.Check(input.Code != "", "code", "must be provided")
+	v.Check(user.TwoFactorEnabled, "two_factor", "is not enabled")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	match, err := app.verifySecondFactor(user, input.Code, "")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !match {
+		v.AddError("code", "is invalid or expired")
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	codes, err := app.models.RecoveryCodes.Replace(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.audit(r, "update", "two_factor", user.ID, nil, envelope{"recovery_codes": len(codes)})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"recovery_codes": codes}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getTwoFactorHandler - состояние 2FA текущего пользователя
+func (app *application) getTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	remaining, err := app.models.RecoveryCodes.CountUnused(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{
+		"enabled":                  user.TwoFactorEnabled,
+		"required":                 app.roleRequiresTwoFactor(user.Role),
+		"recovery_codes_remaining": remaining,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/w3qxst1ck/cs2-grenades/internal/data"
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+// setGrenadeVerificationHandler отмечает раскидку как проверенную на сборке build,
+// сломанную или требующую перепроверки
+func (app *application) setGrenadeVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	grenade, err := app.models.Grenades.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Status string `json:"status"`
+		Build  string `json:"build"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateVerification(input.Status, input.Build, v); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	before := auditSnapshot(grenade)
+
+	err = app.models.Grenades.SetVerification(grenade, input.Status, input.Build, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.clearGrenadeCache(grenade.Map)
+	app.audit(r, "update", "grenade", grenade.ID, before, grenade)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"grenade": grenade}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// mapUpdatedHandler вызывается после обновления карты, все проверенные раскидки карты
+// отправляются на перепроверку
+func (app *application) mapUpdatedHandler(w http.ResponseWriter, r *http.Request) {
+	csMap := httprouter.ParamsFromContext(r.Context()).ByName("map")
+
+	v := validator.New()
+	v.Check(len(csMap) <= 100, "map", "must not be grater than 100 bytes")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Erorrs)
+		return
+	}
+
+	ids, err := app.models.Grenades.MarkMapUpdated(csMap, app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if len(ids) > 0 {
+		app.clearGrenadeCache(csMap)
+	}
+
+	flagged := len(ids)
+
+	// у карты нет id, поэтому одна запись на карту со списком помеченных раскидок,
+	// снимки отдельных раскидок есть в их ревизиях
+	app.audit(r, "update", "map", 0, nil, envelope{"map": csMap, "flagged": flagged, "grenade_ids": ids})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"map": csMap, "flagged": flagged}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
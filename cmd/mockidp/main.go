@@ -0,0 +1,290 @@
+// mockidp - локальный провайдер входа для разработки и проверки /v1/auth/:provider.
+// Поддерживает OpenID Connect (authorization code, id_token RS256) и Steam OpenID 2.0.
+// Вход подтверждается сразу, без формы, пользователь задается флагами.
+//
+// Пример:
+//
+//	go run ./cmd/mockidp -addr :9000
+//	go run ./cmd/api -oidc-issuer http://localhost:9000 -oidc-client-id api -oidc-client-secret secret \
+//		-oidc-redirect-url http://localhost:4000/v1/auth/oidc/callback \
+//		-steam-endpoint http://localhost:9000/steam/openid/login \
+//		-steam-return-url http://localhost:4000/v1/auth/steam/callback -steam-realm http://localhost:4000
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const keyID = "mock-key"
+
+type config struct {
+	addr         string
+	issuer       string
+	clientID     string
+	clientSecret string
+	subject      string
+	email        string
+	name         string
+	steamID      string
+}
+
+type server struct {
+	config config
+	logger *log.Logger
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]string // code -> nonce
+}
+
+func main() {
+	var cfg config
+
+	flag.StringVar(&cfg.addr, "addr", ":9000", "Listen address")
+	flag.StringVar(&cfg.issuer, "issuer", "http://localhost:9000", "Issuer URL, must match the address the API uses")
+	flag.StringVar(&cfg.clientID, "client-id", "api", "Expected client ID")
+	flag.StringVar(&cfg.clientSecret, "client-secret", "secret", "Expected client secret")
+	flag.StringVar(&cfg.subject, "sub", "mock-user-1", "Subject of the signed in user")
+	flag.StringVar(&cfg.email, "email", "player@example.com", "Email of the signed in user")
+	flag.StringVar(&cfg.name, "name", "Mock Player", "Name of the signed in user")
+	flag.StringVar(&cfg.steamID, "steam-id", "76561197960287930", "SteamID64 of the signed in Steam user")
+	flag.Parse()
+
+	logger := log.New(os.Stdout, "", log.Ldate|log.Ltime)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	srv := &server{
+		config: cfg,
+		logger: logger,
+		key:    key,
+		codes:  make(map[string]string),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", srv.discovery)
+	mux.HandleFunc("/authorize", srv.authorize)
+	mux.HandleFunc("/token", srv.token)
+	mux.HandleFunc("/jwks", srv.jwks)
+	mux.HandleFunc("/steam/openid/login", srv.steam)
+
+	logger.Printf("mock identity provider listening on %s, issuer %s", cfg.addr, cfg.issuer)
+	logger.Fatal(http.ListenAndServe(cfg.addr, mux))
+}
+
+func (s *server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.config.issuer,
+		"authorization_endpoint":                s.config.issuer + "/authorize",
+		"token_endpoint":                        s.config.issuer + "/token",
+		"jwks_uri":                              s.config.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+	})
+}
+
+// authorize сразу возвращает пользователя на redirect_uri с кодом
+func (s *server) authorize(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	if qs.Get("client_id") != s.config.clientID || qs.Get("response_type") != "code" {
+		http.Error(w, "invalid client_id or response_type", http.StatusBadRequest)
+		return
+	}
+
+	redirect, err := url.Parse(qs.Get("redirect_uri"))
+	if err != nil || redirect.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code := randomString()
+
+	s.mu.Lock()
+	s.codes[code] = qs.Get("nonce")
+	s.mu.Unlock()
+
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", qs.Get("state"))
+	redirect.RawQuery = params.Encode()
+
+	s.logger.Printf("authorize: issued code for %s", s.config.subject)
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	if clientID != s.config.clientID || clientSecret != s.config.clientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	code := r.PostForm.Get("code")
+
+	s.mu.Lock()
+	nonce, ok := s.codes[code]
+	delete(s.codes, code)
+	s.mu.Unlock()
+
+	if r.PostForm.Get("grant_type") != "authorization_code" || !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+
+	idToken, err := s.sign(map[string]interface{}{
+		"iss":            s.config.issuer,
+		"sub":            s.config.subject,
+		"aud":            s.config.clientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          nonce,
+		"email":          s.config.email,
+		"email_verified": true,
+		"name":           s.config.name,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (s *server) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+		}},
+	})
+}
+
+// steam - checkid_setup возвращает пользователя на return_to, check_authentication подтверждает ответ
+func (s *server) steam(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Form.Get("openid.mode") {
+	case "checkid_setup":
+		returnTo, err := url.Parse(r.Form.Get("openid.return_to"))
+		if err != nil || returnTo.Scheme == "" {
+			http.Error(w, "invalid openid.return_to", http.StatusBadRequest)
+			return
+		}
+
+		claimedID := "https://steamcommunity.com/openid/id/" + s.config.steamID
+
+		params := returnTo.Query()
+		params.Set("openid.ns", "http://specs.openid.net/auth/2.0")
+		params.Set("openid.mode", "id_res")
+		params.Set("openid.op_endpoint", s.config.issuer+"/steam/openid/login")
+		params.Set("openid.claimed_id", claimedID)
+		params.Set("openid.identity", claimedID)
+		params.Set("openid.return_to", r.Form.Get("openid.return_to"))
+		params.Set("openid.response_nonce", time.Now().UTC().Format(time.RFC3339)+randomString())
+		params.Set("openid.assoc_handle", "1234567890")
+		params.Set("openid.signed", "signed,op_endpoint,claimed_id,identity,return_to,response_nonce,assoc_handle")
+		params.Set("openid.sig", randomString())
+		returnTo.RawQuery = params.Encode()
+
+		s.logger.Printf("steam: signed in %s", s.config.steamID)
+
+		http.Redirect(w, r, returnTo.String(), http.StatusFound)
+
+	case "check_authentication":
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "ns:http://specs.openid.net/auth/2.0\nis_valid:true\n")
+
+	default:
+		http.Error(w, "unsupported openid.mode", http.StatusBadRequest)
+	}
+}
+
+func (s *server) sign(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	hashed := sha256.Sum256([]byte(signingInput))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return strings.TrimRight(base64.RawURLEncoding.EncodeToString(b), "=")
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
@@ -15,6 +15,7 @@ require (
 	github.com/aws/aws-sdk-go-v2/feature/s3/manager v1.17.7
 	github.com/aws/aws-sdk-go-v2/service/s3 v1.58.2
 	github.com/patrickmn/go-cache v2.1.0+incompatible
+	golang.org/x/crypto v0.21.0
 )
 
 require (
@@ -0,0 +1,345 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testClientID = "client-id"
+	testNonce    = "nonce-123"
+)
+
+// testIssuer - OIDC провайдер на httptest: discovery, jwks и token endpoint
+type testIssuer struct {
+	server *httptest.Server
+
+	mu         sync.Mutex
+	keys       map[string]*rsa.PrivateKey
+	jwksHits   int
+	tokenToIss string
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+
+	issuer := &testIssuer{keys: make(map[string]*rsa.PrivateKey)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 issuer.server.URL,
+			"authorization_endpoint": issuer.server.URL + "/authorize",
+			"token_endpoint":         issuer.server.URL + "/token",
+			"jwks_uri":               issuer.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		issuer.mu.Lock()
+		defer issuer.mu.Unlock()
+
+		issuer.jwksHits++
+
+		keys := []map[string]string{}
+		for kid, key := range issuer.keys {
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"use": "sig",
+				"kid": kid,
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != testClientID || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+
+		issuer.mu.Lock()
+		token := issuer.tokenToIss
+		issuer.mu.Unlock()
+
+		json.NewEncoder(w).Encode(map[string]string{"id_token": token})
+	})
+
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+
+	return issuer
+}
+
+func (i *testIssuer) addKey(t *testing.T, kid string) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i.mu.Lock()
+	i.keys[kid] = key
+	i.mu.Unlock()
+
+	return key
+}
+
+func (i *testIssuer) provider() *OIDCProvider {
+	return NewOIDCProvider(OIDCConfig{
+		Issuer:       i.server.URL,
+		ClientID:     testClientID,
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/callback",
+	}, i.server.Client())
+}
+
+// claims - валидные claims токена, тесты портят нужное поле
+func (i *testIssuer) claims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":   i.server.URL,
+		"sub":   "user-1",
+		"aud":   testClientID,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": testNonce,
+		"email": "user@example.com",
+		"name":  "User",
+	}
+}
+
+func signTestToken(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+
+	segment := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	signed := segment(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"}) + "." + segment(claims)
+
+	hashed := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestOIDCExchange(t *testing.T) {
+	issuer := newTestIssuer(t)
+	key := issuer.addKey(t, "k1")
+	issuer.tokenToIss = signTestToken(t, key, "k1", issuer.claims())
+
+	identity, err := issuer.provider().Exchange(context.Background(), "code", testNonce)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := Identity{Provider: "oidc", Subject: "user-1", Email: "user@example.com", Name: "User"}
+	if *identity != want {
+		t.Errorf("identity = %+v, want %+v", *identity, want)
+	}
+}
+
+func TestOIDCVerifyIDTokenRejects(t *testing.T) {
+	issuer := newTestIssuer(t)
+	key := issuer.addKey(t, "k1")
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		token  func() string
+		reason string
+	}{
+		{
+			name:   "signed by another key",
+			token:  func() string { return signTestToken(t, otherKey, "k1", issuer.claims()) },
+			reason: "bad signature",
+		},
+		{
+			name: "tampered payload",
+			token: func() string {
+				parts := strings.Split(signTestToken(t, key, "k1", issuer.claims()), ".")
+				claims := issuer.claims()
+				claims["sub"] = "admin"
+				b, _ := json.Marshal(claims)
+				parts[1] = base64.RawURLEncoding.EncodeToString(b)
+				return strings.Join(parts, ".")
+			},
+			reason: "bad signature",
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				claims := issuer.claims()
+				claims["iss"] = "https://evil.example.com"
+				return signTestToken(t, key, "k1", claims)
+			},
+			reason: "issuer mismatch",
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				claims := issuer.claims()
+				claims["aud"] = "other-client"
+				return signTestToken(t, key, "k1", claims)
+			},
+			reason: "audience mismatch",
+		},
+		{
+			name: "audience list without client",
+			token: func() string {
+				claims := issuer.claims()
+				claims["aud"] = []string{"a", "b"}
+				return signTestToken(t, key, "k1", claims)
+			},
+			reason: "audience mismatch",
+		},
+		{
+			name: "expired beyond clock skew",
+			token: func() string {
+				claims := issuer.claims()
+				claims["exp"] = time.Now().Add(-clockSkew - time.Minute).Unix()
+				return signTestToken(t, key, "k1", claims)
+			},
+			reason: "token expired",
+		},
+		{
+			name: "nonce mismatch",
+			token: func() string {
+				claims := issuer.claims()
+				claims["nonce"] = "other-nonce"
+				return signTestToken(t, key, "k1", claims)
+			},
+			reason: "nonce mismatch",
+		},
+		{
+			name: "missing subject",
+			token: func() string {
+				claims := issuer.claims()
+				delete(claims, "sub")
+				return signTestToken(t, key, "k1", claims)
+			},
+			reason: "missing subject",
+		},
+		{
+			name:   "unknown key",
+			token:  func() string { return signTestToken(t, key, "k-unknown", issuer.claims()) },
+			reason: "unknown key",
+		},
+		{
+			name: "alg none",
+			token: func() string {
+				parts := strings.Split(signTestToken(t, key, "k1", issuer.claims()), ".")
+				parts[0] = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"k1"}`))
+				return parts[0] + "." + parts[1] + "."
+			},
+			reason: "unsupported alg",
+		},
+		{
+			name:   "malformed",
+			token:  func() string { return "not-a-jwt" },
+			reason: "malformed token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := issuer.provider().verifyIDToken(context.Background(), tt.token(), testNonce)
+			if !errors.Is(err, ErrInvalidIDToken) {
+				t.Fatalf("error = %v, want ErrInvalidIDToken", err)
+			}
+
+			if !strings.Contains(err.Error(), tt.reason) {
+				t.Errorf("error = %q, want it to contain %q", err.Error(), tt.reason)
+			}
+		})
+	}
+}
+
+func TestOIDCVerifyIDTokenClockSkew(t *testing.T) {
+	issuer := newTestIssuer(t)
+	key := issuer.addKey(t, "k1")
+
+	claims := issuer.claims()
+	claims["exp"] = time.Now().Add(-clockSkew / 2).Unix()
+
+	_, err := issuer.provider().verifyIDToken(context.Background(), signTestToken(t, key, "k1", claims), testNonce)
+	if err != nil {
+		t.Errorf("token expired within clock skew: unexpected error: %v", err)
+	}
+}
+
+func TestOIDCKeyRotation(t *testing.T) {
+	issuer := newTestIssuer(t)
+	oldKey := issuer.addKey(t, "k1")
+
+	provider := issuer.provider()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := provider.verifyIDToken(ctx, signTestToken(t, oldKey, "k1", issuer.claims()), testNonce); err != nil {
+			t.Fatalf("old key: unexpected error: %v", err)
+		}
+	}
+
+	if issuer.jwksHits != 1 {
+		t.Errorf("jwks fetched %d times, want 1: known keys must be cached", issuer.jwksHits)
+	}
+
+	// провайдер сменил ключ, новый kid вызывает перезагрузку jwks
+	issuer.mu.Lock()
+	delete(issuer.keys, "k1")
+	issuer.mu.Unlock()
+	newKey := issuer.addKey(t, "k2")
+
+	if _, err := provider.verifyIDToken(ctx, signTestToken(t, newKey, "k2", issuer.claims()), testNonce); err != nil {
+		t.Fatalf("rotated key: unexpected error: %v", err)
+	}
+
+	if issuer.jwksHits != 2 {
+		t.Errorf("jwks fetched %d times, want 2 after an unknown kid", issuer.jwksHits)
+	}
+
+	// отозванный ключ больше не принимается
+	_, err := provider.verifyIDToken(ctx, signTestToken(t, oldKey, "k1", issuer.claims()), testNonce)
+	if !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("removed key: error = %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestOIDCDiscoveryIssuerMismatch(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	provider := NewOIDCProvider(OIDCConfig{Issuer: issuer.server.URL + "/", ClientID: testClientID}, issuer.server.Client())
+
+	_, err := provider.AuthCodeURL(context.Background(), "state", testNonce)
+	if !errors.Is(err, ErrProvider) {
+		t.Errorf("error = %v, want ErrProvider", err)
+	}
+}
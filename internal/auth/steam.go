@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Вход через Steam по OpenID 2.0. Steam не отдает email и имя, пользователь определяется
+// по SteamID64 из openid.claimed_id, а подлинность ответа проверяется запросом
+// check_authentication обратно в Steam.
+
+const (
+	SteamOpenIDEndpoint = "https://steamcommunity.com/openid/login"
+
+	openIDNamespace      = "http://specs.openid.net/auth/2.0"
+	openIDIdentifierPick = "http://specs.openid.net/auth/2.0/identifier_select"
+)
+
+var (
+	ErrInvalidSteamResponse = errors.New("invalid steam openid response")
+
+	steamClaimedIDRX = regexp.MustCompile(`^https://steamcommunity\.com/openid/id/(\d{17})$`)
+)
+
+type SteamConfig struct {
+	// Endpoint - адрес OpenID Steam, можно заменить на локальный mock
+	Endpoint string
+	// ReturnURL - callback API, Realm - адрес сайта, который Steam показывает пользователю
+	ReturnURL string
+	Realm     string
+}
+
+type SteamProvider struct {
+	config SteamConfig
+	client *http.Client
+}
+
+func NewSteamProvider(config SteamConfig, client *http.Client) *SteamProvider {
+	if config.Endpoint == "" {
+		config.Endpoint = SteamOpenIDEndpoint
+	}
+
+	return &SteamProvider{config: config, client: client}
+}
+
+func (p *SteamProvider) Name() string {
+	return "steam"
+}
+
+// returnTo - callback с state, Steam вернет его без изменений
+func (p *SteamProvider) returnTo(state string) string {
+	separator := "?"
+	if strings.Contains(p.config.ReturnURL, "?") {
+		separator = "&"
+	}
+	return p.config.ReturnURL + separator + "state=" + url.QueryEscape(state)
+}
+
+// AuthURL - адрес, на который нужно отправить пользователя для входа
+func (p *SteamProvider) AuthURL(state string) string {
+	params := url.Values{}
+	params.Set("openid.ns", openIDNamespace)
+	params.Set("openid.mode", "checkid_setup")
+	params.Set("openid.return_to", p.returnTo(state))
+	params.Set("openid.realm", p.config.Realm)
+	params.Set("openid.identity", openIDIdentifierPick)
+	params.Set("openid.claimed_id", openIDIdentifierPick)
+
+	return p.config.Endpoint + "?" + params.Encode()
+}
+
+// Verify проверяет параметры callback и возвращает пользователя со SteamID64 в Subject
+func (p *SteamProvider) Verify(ctx context.Context, query url.Values, state string) (*Identity, error) {
+	switch {
+	case query.Get("openid.mode") != "id_res":
+		return nil, fmt.Errorf("%w: unexpected mode %q", ErrInvalidSteamResponse, query.Get("openid.mode"))
+	case query.Get("openid.op_endpoint") != p.config.Endpoint:
+		return nil, fmt.Errorf("%w: endpoint mismatch", ErrInvalidSteamResponse)
+	case query.Get("openid.return_to") != p.returnTo(state):
+		return nil, fmt.Errorf("%w: return_to mismatch", ErrInvalidSteamResponse)
+	}
+
+	matches := steamClaimedIDRX.FindStringSubmatch(query.Get("openid.claimed_id"))
+	if matches == nil {
+		return nil, fmt.Errorf("%w: malformed claimed_id", ErrInvalidSteamResponse)
+	}
+
+	// подпись проверяет сам Steam, отправляем ему подписанные поля с mode=check_authentication
+	form := url.Values{}
+	for key, values := range query {
+		if strings.HasPrefix(key, "openid.") {
+			form[key] = values
+		}
+	}
+	form.Set("openid.mode", "check_authentication")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: check_authentication returned %d", ErrProvider, res.StatusCode)
+	}
+
+	valid := false
+
+	// ответ в формате key-value, по строке на пару
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "is_valid:true" {
+			valid = true
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !valid {
+		return nil, fmt.Errorf("%w: assertion rejected", ErrInvalidSteamResponse)
+	}
+
+	return &Identity{
+		Provider: p.Name(),
+		Subject:  matches[1],
+	}, nil
+}
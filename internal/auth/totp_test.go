@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// секрет из RFC 6238 для HMAC-SHA1: ASCII "12345678901234567890"
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPRFC6238Vectors(t *testing.T) {
+	// эталонные значения из приложения B RFC 6238, у 6-значного кода берутся последние 6 цифр
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	key, err := totpEncoding.DecodeString(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		step := tt.unix / totpPeriod
+
+		if code := totpCode(key, step); code != tt.code {
+			t.Errorf("time %d: code = %s, want %s", tt.unix, code, tt.code)
+		}
+
+		gotStep, ok := ValidateTOTP(rfc6238Secret, tt.code, time.Unix(tt.unix, 0))
+		if !ok || gotStep != step {
+			t.Errorf("time %d: ValidateTOTP = %d, %v, want %d, true", tt.unix, gotStep, ok, step)
+		}
+	}
+}
+
+func TestValidateTOTPClockSkew(t *testing.T) {
+	key, _ := totpEncoding.DecodeString(rfc6238Secret)
+
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / totpPeriod
+
+	tests := []struct {
+		name  string
+		step  int64
+		valid bool
+	}{
+		{"current step", current, true},
+		{"previous step", current - 1, true},
+		{"next step", current + 1, true},
+		{"two steps behind", current - 2, false},
+		{"two steps ahead", current + 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfc6238Secret, totpCode(key, tt.step), now)
+			if ok != tt.valid {
+				t.Fatalf("valid = %v, want %v", ok, tt.valid)
+			}
+			if ok && step != tt.step {
+				t.Errorf("step = %d, want %d", step, tt.step)
+			}
+		})
+	}
+}
+
+// TestValidateTOTPReplayStep проверяет шаг, по которому UseTOTPStep отсекает повтор:
+// повторный код дает тот же шаг, а код из прошлого шага - меньший
+func TestValidateTOTPReplayStep(t *testing.T) {
+	key, _ := totpEncoding.DecodeString(rfc6238Secret)
+
+	now := time.Unix(1234567890, 0)
+	current := now.Unix() / totpPeriod
+	code := totpCode(key, current)
+
+	first, ok := ValidateTOTP(rfc6238Secret, code, now)
+	if !ok {
+		t.Fatal("code rejected")
+	}
+
+	// тот же код через 20 секунд еще в окне, но шаг не растет
+	replayed, ok := ValidateTOTP(rfc6238Secret, code, now.Add(20*time.Second))
+	if !ok {
+		t.Fatal("code rejected within skew window")
+	}
+	if replayed != first {
+		t.Errorf("replayed step = %d, want %d", replayed, first)
+	}
+
+	// код предыдущего шага после принятого текущего должен отклоняться по шагу
+	previous, ok := ValidateTOTP(rfc6238Secret, totpCode(key, current-1), now)
+	if !ok || previous >= first {
+		t.Errorf("previous code step = %d, %v, want less than %d", previous, ok, first)
+	}
+}
+
+func TestValidateTOTPInput(t *testing.T) {
+	key, _ := totpEncoding.DecodeString(rfc6238Secret)
+
+	now := time.Unix(1111111111, 0)
+	code := totpCode(key, now.Unix()/totpPeriod)
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		valid  bool
+	}{
+		{"spaces in code", rfc6238Secret, code[:3] + " " + code[3:], true},
+		{"lowercase secret", strings.ToLower(rfc6238Secret), code, true},
+		{"wrong code", rfc6238Secret, "000000", code == "000000"},
+		{"short code", rfc6238Secret, code[:5], false},
+		{"long code", rfc6238Secret, code + "1", false},
+		{"invalid secret", "not base32!", code, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(tt.secret, tt.code, now); ok != tt.valid {
+				t.Errorf("valid = %v, want %v", ok, tt.valid)
+			}
+		})
+	}
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Errorf("secret %q: key length %d, err %v, want 20 bytes", secret, len(key), err)
+	}
+
+	other, _ := NewTOTPSecret()
+	if other == secret {
+		t.Error("secrets must be random")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(TOTPProvisioningURI("CS2 Grenades", "user@example.com", rfc6238Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/CS2 Grenades:user@example.com" {
+		t.Errorf("uri = %s", uri)
+	}
+
+	qs := uri.Query()
+	if qs.Get("secret") != rfc6238Secret || qs.Get("digits") != "6" || qs.Get("period") != "30" {
+		t.Errorf("query = %v", qs)
+	}
+}
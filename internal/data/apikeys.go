@@ -0,0 +1,269 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+const apiKeyPrefix = "cs2g_"
+
+// RateLimitTier - лимит запросов для API ключа
+type RateLimitTier struct {
+	RPS   float64
+	Burst int
+}
+
+// APIKeyTiers - тарифы лимитов, basic по умолчанию, остальные выдает админ
+var APIKeyTiers = map[string]RateLimitTier{
+	"basic":    {RPS: 5, Burst: 10},
+	"standard": {RPS: 20, Burst: 40},
+	"premium":  {RPS: 100, Burst: 200},
+}
+
+// apiKeyLastUsedInterval - точность last_used_at ключа
+const apiKeyLastUsedInterval = time.Minute
+
+// APIKeyScopes - права, которые можно выдать ключу
+var APIKeyScopes = []string{
+	PermissionGrenadesWrite,
+	PermissionGrenadesSubmit,
+	PermissionImagesWrite,
+	PermissionModerationManage,
+}
+
+// APIKey - ключ для сервисов, в БД хранится только sha256 от Plaintext,
+// Prefix показывается в списке ключей, чтобы их можно было различить
+type APIKey struct {
+	ID         int64       `json:"id"`
+	UserID     int64       `json:"user_id"`
+	Name       string      `json:"name"`
+	Plaintext  string      `json:"key,omitempty"`
+	Prefix     string      `json:"prefix"`
+	Hash       []byte      `json:"-"`
+	Scopes     Permissions `json:"scopes"`
+	Tier       string      `json:"tier"`
+	CreatedAt  time.Time   `json:"created_at"`
+	LastUsedAt *time.Time  `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time  `json:"revoked_at,omitempty"`
+}
+
+// ValidateAPIKey проверяет ключ, userPermissions - права создателя, ключ не может иметь больше прав
+func ValidateAPIKey(v *validator.Validator, key *APIKey, userPermissions Permissions) {
+	v.Check(key.Name != "", "name", "must be provided")
+	v.Check(len(key.Name) <= 100, "name", "must not be grater than 100 bytes")
+
+	v.Check(len(key.Scopes) > 0, "scopes", "must contain at least 1 scope")
+	for _, scope := range key.Scopes {
+		v.Check(v.In(scope, APIKeyScopes), "scopes", "must contain only known scopes")
+		v.Check(userPermissions.Include(scope), "scopes", "must not exceed your own permissions")
+	}
+
+	_, ok := APIKeyTiers[key.Tier]
+	v.Check(ok, "tier", "value of tier must be basic|standard|premium")
+}
+
+func ValidateAPIKeyPlaintext(v *validator.Validator, keyPlaintext string) {
+	v.Check(strings.HasPrefix(keyPlaintext, apiKeyPrefix), "key", "must be a valid api key")
+	v.Check(len(keyPlaintext) == len(apiKeyPrefix)+32, "key", "must be a valid api key")
+}
+
+func generateAPIKey(key *APIKey) error {
+	randomBytes := make([]byte, 20)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return err
+	}
+
+	key.Plaintext = apiKeyPrefix + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes))
+	key.Prefix = key.Plaintext[:len(apiKeyPrefix)+6]
+
+	hash := sha256.Sum256([]byte(key.Plaintext))
+	key.Hash = hash[:]
+
+	return nil
+}
+
+type APIKeyModel struct {
+	DB *sql.DB
+}
+
+// Insert генерирует ключ, Plaintext доступен только в ответе на создание
+func (m APIKeyModel) Insert(key *APIKey) error {
+	if err := generateAPIKey(key); err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO api_keys (user_id, name, prefix, hash, scopes, tier)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{key.UserID, key.Name, key.Prefix, key.Hash, pq.Array([]string(key.Scopes)), key.Tier}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&key.ID, &key.CreatedAt)
+}
+
+func (k *APIKey) fields() []interface{} {
+	return []interface{}{
+		&k.ID,
+		&k.UserID,
+		&k.Name,
+		&k.Prefix,
+		pq.Array((*[]string)(&k.Scopes)),
+		&k.Tier,
+		&k.CreatedAt,
+		&k.LastUsedAt,
+		&k.RevokedAt,
+	}
+}
+
+func (m APIKeyModel) Get(id int64) (*APIKey, error) {
+	query := `
+	SELECT id, user_id, name, prefix, scopes, tier, created_at, last_used_at, revoked_at
+	FROM api_keys
+	WHERE id = $1`
+
+	var key APIKey
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(key.fields()...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &key, nil
+}
+
+// GetAllForUser возвращает ключи пользователя, включая отозванные
+func (m APIKeyModel) GetAllForUser(userID int64) ([]*APIKey, error) {
+	query := `
+	SELECT id, user_id, name, prefix, scopes, tier, created_at, last_used_at, revoked_at
+	FROM api_keys
+	WHERE user_id = $1
+	ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*APIKey{}
+
+	for rows.Next() {
+		var key APIKey
+
+		err := rows.Scan(key.fields()...)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, &key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Revoke отзывает ключ, отозванный ключ остается в списке
+func (m APIKeyModel) Revoke(key *APIKey) error {
+	query := `
+	UPDATE api_keys
+	SET revoked_at = NOW()
+	WHERE id = $1 AND revoked_at IS NULL
+	RETURNING revoked_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, key.ID).Scan(&key.RevokedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Authenticate находит действующий ключ вместе с владельцем и обновляет last_used_at
+func (m APIKeyModel) Authenticate(keyPlaintext string) (*APIKey, *User, error) {
+	keyHash := sha256.Sum256([]byte(keyPlaintext))
+
+	query := `
+	SELECT api_keys.id, api_keys.user_id, api_keys.name, api_keys.prefix, api_keys.scopes, api_keys.tier,
+		api_keys.created_at, api_keys.last_used_at, api_keys.revoked_at,
+		` + userColumns + `
+	FROM api_keys
+	INNER JOIN users ON users.id = api_keys.user_id
+	WHERE api_keys.hash = $1 AND api_keys.revoked_at IS NULL`
+
+	var (
+		key  APIKey
+		user User
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	dest := append(key.fields(), user.fields()...)
+
+	err := m.DB.QueryRowContext(ctx, query, keyHash[:]).Scan(dest...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil, ErrRecordNotFound
+		default:
+			return nil, nil, err
+		}
+	}
+
+	// last_used_at обновляется не чаще раза в apiKeyLastUsedInterval, а не на каждый запрос
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > apiKeyLastUsedInterval {
+		err = m.touch(ctx, &key)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return &key, &user, nil
+}
+
+func (m APIKeyModel) touch(ctx context.Context, key *APIKey) error {
+	query := `
+	UPDATE api_keys
+	SET last_used_at = NOW()
+	WHERE id = $1
+	RETURNING last_used_at`
+
+	return m.DB.QueryRowContext(ctx, query, key.ID).Scan(&key.LastUsedAt)
+}
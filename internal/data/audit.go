@@ -0,0 +1,214 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+// AuditActions - create/update/delete, restore из корзины и purge при окончательном удалении
+var AuditActions = []string{"create", "update", "delete", "restore", "purge"}
+
+var AuditEntityTypes = []string{"grenade", "image", "relation", "tag", "target", "execute", "report", "map", "callout", "user", "user_identity", "two_factor", "token", "api_key"}
+
+// AuditEntry - запись журнала аудита. Before и After - JSON сущности до и после изменения,
+// у записей фоновых задач нет пользователя, IP и id запроса
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   int64           `json:"entity_id,omitempty"`
+	ActorID    int64           `json:"actor_id,omitempty"`
+	APIKeyID   int64           `json:"api_key_id,omitempty"`
+	IP         string          `json:"ip,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// AuditSearch - фильтры журнала, нулевые значения не фильтруют
+type AuditSearch struct {
+	Actions     ListFilter
+	EntityTypes ListFilter
+	EntityID    int64
+	ActorID     int64
+	RequestID   string
+	From        time.Time
+	To          time.Time
+}
+
+func ValidateAuditSearch(v *validator.Validator, search AuditSearch) {
+	ValidateListFilter(v, "action", search.Actions, AuditActions)
+	ValidateListFilter(v, "entity_type", search.EntityTypes, AuditEntityTypes)
+
+	v.Check(search.EntityID >= 0, "entity_id", "must be a positive integer")
+	v.Check(search.ActorID >= 0, "actor_id", "must be a positive integer")
+	v.Check(len(search.RequestID) <= 128, "request_id", "must not be grater than 128 bytes")
+	v.Check(search.From.IsZero() || search.To.IsZero() || search.From.Before(search.To), "from", "must be before to")
+}
+
+func (s AuditSearch) where(args *queryArgs) string {
+	conditions := []string{"true"}
+
+	conditions = append(conditions, s.Actions.condition("action", args)...)
+	conditions = append(conditions, s.EntityTypes.condition("entity_type", args)...)
+
+	if s.EntityID > 0 {
+		conditions = append(conditions, "entity_id = "+args.add(s.EntityID))
+	}
+
+	if s.ActorID > 0 {
+		conditions = append(conditions, "actor_id = "+args.add(s.ActorID))
+	}
+
+	if s.RequestID != "" {
+		conditions = append(conditions, "request_id = "+args.add(s.RequestID))
+	}
+
+	if !s.From.IsZero() {
+		conditions = append(conditions, "created_at >= "+args.add(s.From))
+	}
+
+	if !s.To.IsZero() {
+		conditions = append(conditions, "created_at < "+args.add(s.To))
+	}
+
+	return strings.Join(conditions, " AND ")
+}
+
+const auditColumns = `id, action, entity_type, COALESCE(entity_id, 0), COALESCE(actor_id, 0), COALESCE(api_key_id, 0),
+	COALESCE(host(ip), ''), request_id, before_data, after_data, created_at`
+
+// снимки сканируются как []byte, в json.RawMessage database/sql не умеет записывать NULL
+func (e *AuditEntry) fields() []interface{} {
+	return []interface{}{
+		&e.ID,
+		&e.Action,
+		&e.EntityType,
+		&e.EntityID,
+		&e.ActorID,
+		&e.APIKeyID,
+		&e.IP,
+		&e.RequestID,
+		(*[]byte)(&e.Before),
+		(*[]byte)(&e.After),
+		&e.CreatedAt,
+	}
+}
+
+type AuditLogModel struct {
+	DB *sql.DB
+}
+
+func (m AuditLogModel) Insert(entry *AuditEntry) error {
+	query := `
+	INSERT INTO audit_log (action, entity_type, entity_id, actor_id, api_key_id, ip, request_id, before_data, after_data)
+	VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, 0), NULLIF($5, 0), NULLIF($6, '')::inet, $7, $8, $9)
+	RETURNING id, created_at`
+
+	args := []interface{}{
+		entry.Action,
+		entry.EntityType,
+		entry.EntityID,
+		entry.ActorID,
+		entry.APIKeyID,
+		entry.IP,
+		entry.RequestID,
+		nullJSON(entry.Before),
+		nullJSON(entry.After),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+func (m AuditLogModel) GetAll(search AuditSearch, filters Filters) ([]*AuditEntry, Metadata, error) {
+	args := queryArgs{}
+
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), %s
+	FROM audit_log
+	WHERE %s
+	ORDER BY %s %s, id DESC
+	LIMIT %s OFFSET %s`, auditColumns, search.where(&args), filters.sortColumn(), filters.sortDirection(), args.add(filters.limit()), args.add(filters.offset()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	entries := []*AuditEntry{}
+
+	for rows.Next() {
+		var entry AuditEntry
+
+		err := rows.Scan(append([]interface{}{&totalRecords}, entry.fields()...)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return entries, metadata, nil
+}
+
+// Export построчно передает в fn все записи журнала по фильтрам в порядке добавления,
+// записи не собираются в память, поэтому выгрузка не ограничена размером страницы
+func (m AuditLogModel) Export(ctx context.Context, search AuditSearch, fn func(*AuditEntry) error) error {
+	args := queryArgs{}
+
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM audit_log
+	WHERE %s
+	ORDER BY id ASC`, auditColumns, search.where(&args))
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry AuditEntry
+
+		err := rows.Scan(entry.fields()...)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// nullJSON - пустой снимок пишется как NULL. Строкой, потому что []byte pq передает как bytea
+func nullJSON(data json.RawMessage) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
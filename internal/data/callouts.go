@@ -0,0 +1,139 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+var ErrDuplicateCallout = errors.New("duplicate callout")
+
+// Callout - название позиции на карте, используется в подсказках поиска
+type Callout struct {
+	ID   int64  `json:"id"`
+	Map  string `json:"map"`
+	Name string `json:"name"`
+}
+
+type CalloutModel struct {
+	DB *sql.DB
+}
+
+func ValidateCallout(callout *Callout, v *validator.Validator) {
+	v.Check(callout.Map != "", "map", "must be provided")
+	v.Check(len(callout.Map) <= 30, "map", "must not be grater than 30 bytes")
+
+	v.Check(callout.Name != "", "name", "must be provided")
+	v.Check(len(callout.Name) <= 100, "name", "must not be grater than 100 bytes")
+}
+
+func (m CalloutModel) Get(id int64) (*Callout, error) {
+	query := `
+	SELECT id, map, name
+	FROM callouts
+	WHERE id = $1`
+
+	var callout Callout
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&callout.ID, &callout.Map, &callout.Name)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &callout, nil
+}
+
+// GetAll возвращает каллауты карты csMap, при пустой csMap - всех карт
+func (m CalloutModel) GetAll(csMap string) ([]*Callout, error) {
+	query := `
+	SELECT id, map, name
+	FROM callouts
+	WHERE map = $1 OR $1 = ''
+	ORDER BY map, name`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, csMap)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	callouts := []*Callout{}
+
+	for rows.Next() {
+		var callout Callout
+
+		err := rows.Scan(&callout.ID, &callout.Map, &callout.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		callouts = append(callouts, &callout)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return callouts, nil
+}
+
+func (m CalloutModel) Insert(callout *Callout) error {
+	query := `
+	INSERT INTO callouts (map, name)
+	VALUES ($1, $2)
+	RETURNING id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, callout.Map, callout.Name).Scan(&callout.ID)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicateCallout
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m CalloutModel) Delete(id int64) error {
+	query := `
+	DELETE FROM callouts
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
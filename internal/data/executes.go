@@ -0,0 +1,332 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+const (
+	executeMaxItems      = 30
+	executeMaxPlayerSlot = 5
+	// длительность раунда 1:55
+	executeMaxThrowOffsetMs = 115_000
+)
+
+// Execute - именованный упорядоченный набор раскидок для одной карты и стороны
+type Execute struct {
+	ID          int64          `json:"id"`
+	Name        string         `json:"name"`
+	Map         string         `json:"map"`
+	Side        string         `json:"side"`
+	Description string         `json:"description"`
+	CreatedAt   time.Time      `json:"created_at"`
+	Version     int32          `json:"version"`
+	Items       []*ExecuteItem `json:"items,omitempty"`
+}
+
+type ExecuteItem struct {
+	GrenadeID     int64    `json:"grenade_id"`
+	PlayerSlot    int      `json:"player_slot"`
+	ThrowOffsetMs int      `json:"throw_offset_ms"`
+	Grenade       *Grenade `json:"grenade,omitempty"`
+}
+
+type ExecuteModel struct {
+	DB *sql.DB
+}
+
+// ValidateExecute проверяет execute, grenades - гранаты из Items по id,
+// каждая граната должна существовать, быть опубликованной и совпадать с execute по карте и стороне
+func ValidateExecute(execute *Execute, grenades map[int64]*Grenade, v *validator.Validator) {
+	v.Check(execute.Name != "", "name", "must be provided")
+	v.Check(len(execute.Name) <= 200, "name", "must not be grater than 200 bytes")
+
+	v.Check(execute.Map != "", "map", "must be provided")
+	v.Check(len(execute.Map) <= 100, "map", "must not be grater than 100 bytes")
+
+	v.Check(execute.Side != "", "side", "must be provided")
+	v.Check(v.In(execute.Side, GrenadeSides), "side", "value of side must be T or CT")
+
+	v.Check(len(execute.Description) <= 700, "description", "must not be grater than 700 bytes")
+
+	v.Check(len(execute.Items) <= executeMaxItems, "items", fmt.Sprintf("must contain a maximum of %d items", executeMaxItems))
+
+	for i, item := range execute.Items {
+		key := fmt.Sprintf("items[%d]", i)
+
+		v.Check(item.PlayerSlot >= 1 && item.PlayerSlot <= executeMaxPlayerSlot, key+".player_slot", fmt.Sprintf("must be between 1 and %d", executeMaxPlayerSlot))
+		v.Check(item.ThrowOffsetMs >= 0 && item.ThrowOffsetMs <= executeMaxThrowOffsetMs, key+".throw_offset_ms", fmt.Sprintf("must be between 0 and %d", executeMaxThrowOffsetMs))
+
+		grenade, ok := grenades[item.GrenadeID]
+		if !ok {
+			v.AddError(key+".grenade_id", "grenade does not exist")
+			continue
+		}
+
+		v.Check(grenade.Status == "published", key+".grenade_id", "grenade must be published")
+		v.Check(grenade.Map == execute.Map, key+".grenade_id", "grenade map must match execute map")
+		v.Check(grenade.Side == execute.Side, key+".grenade_id", "grenade side must match execute side")
+	}
+}
+
+// GrenadeIDs возвращает id гранат из Items
+func (e *Execute) GrenadeIDs() []int64 {
+	ids := make([]int64, len(e.Items))
+	for i, item := range e.Items {
+		ids[i] = item.GrenadeID
+	}
+	return ids
+}
+
+func (m ExecuteModel) Get(id int64) (*Execute, error) {
+	query := `
+	SELECT id, name, map, side, description, created_at, version
+	FROM executes
+	WHERE id = $1`
+
+	var execute Execute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&execute.ID,
+		&execute.Name,
+		&execute.Map,
+		&execute.Side,
+		&execute.Description,
+		&execute.CreatedAt,
+		&execute.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	execute.Items, err = m.getItems(ctx, execute.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &execute, nil
+}
+
+// getItems возвращает только опубликованные гранаты: execute виден всем,
+// а раскидку после публикации могут вернуть на проверку
+func (m ExecuteModel) getItems(ctx context.Context, executeID int64) ([]*ExecuteItem, error) {
+	query := fmt.Sprintf(`
+	SELECT ei.grenade_id, ei.player_slot, ei.throw_offset_ms, %s
+	FROM execute_items ei
+	INNER JOIN grenades ON grenades.id = ei.grenade_id
+	WHERE ei.execute_id = $1 AND grenades.status = 'published' AND grenades.deleted_at IS NULL
+	ORDER BY ei.position`, grenadeColumns)
+
+	rows, err := m.DB.QueryContext(ctx, query, executeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*ExecuteItem{}
+
+	for rows.Next() {
+		var item ExecuteItem
+		var grenade Grenade
+
+		err := rows.Scan(append([]interface{}{&item.GrenadeID, &item.PlayerSlot, &item.ThrowOffsetMs}, grenade.fields()...)...)
+		if err != nil {
+			return nil, err
+		}
+
+		item.Grenade = &grenade
+
+		items = append(items, &item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (m ExecuteModel) Insert(execute *Execute) error {
+	query := `
+	INSERT INTO executes (name, map, side, description)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	args := []interface{}{execute.Name, execute.Map, execute.Side, execute.Description}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&execute.ID, &execute.CreatedAt, &execute.Version)
+	if err != nil {
+		return err
+	}
+
+	if err = insertExecuteItems(ctx, tx, execute); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Update сохраняет execute, items заменяются только при replaceItems: Get возвращает не все items,
+// раскидки из корзины и снятые с публикации скрыты и при перезаписи были бы потеряны
+func (m ExecuteModel) Update(execute *Execute, replaceItems bool) error {
+	query := `
+	UPDATE executes
+	SET name = $1, map = $2, side = $3, description = $4, version = version + 1
+	WHERE id = $5 AND version = $6
+	RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	args := []interface{}{
+		execute.Name,
+		execute.Map,
+		execute.Side,
+		execute.Description,
+		execute.ID,
+		execute.Version,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&execute.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	if replaceItems {
+		_, err = tx.ExecContext(ctx, "DELETE FROM execute_items WHERE execute_id = $1", execute.ID)
+		if err != nil {
+			return err
+		}
+
+		if err = insertExecuteItems(ctx, tx, execute); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func insertExecuteItems(ctx context.Context, tx *sql.Tx, execute *Execute) error {
+	query := `
+	INSERT INTO execute_items (execute_id, position, grenade_id, player_slot, throw_offset_ms)
+	VALUES ($1, $2, $3, $4, $5)`
+
+	for i, item := range execute.Items {
+		_, err := tx.ExecContext(ctx, query, execute.ID, i, item.GrenadeID, item.PlayerSlot, item.ThrowOffsetMs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m ExecuteModel) Delete(id int64) error {
+	query := `
+	DELETE FROM executes
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetAll возвращает execute без Items
+func (m ExecuteModel) GetAll(csMap string, side string, filters Filters) ([]*Execute, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, name, map, side, description, created_at, version
+	FROM executes
+	WHERE (map = $1 OR $1 = '') AND (side = $2 OR $2 = '')
+	ORDER BY %s %s, id ASC
+	LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{csMap, side, filters.limit(), filters.offset()}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	executes := []*Execute{}
+
+	for rows.Next() {
+		var execute Execute
+
+		err := rows.Scan(
+			&totalRecords,
+			&execute.ID,
+			&execute.Name,
+			&execute.Map,
+			&execute.Side,
+			&execute.Description,
+			&execute.CreatedAt,
+			&execute.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		executes = append(executes, &execute)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return executes, metadata, nil
+}
@@ -1,10 +1,142 @@
 package data
 
-import "strings"
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
 
 type Filters struct {
+	Page         int
+	PageSize     int
 	Sort         string
 	SortSafeList []string
+	Cursor       string
+}
+
+type Metadata struct {
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty"`
+	TotalRecords int    `json:"total_records,omitempty"`
+	NextCursor   string `json:"next_cursor,omitempty"`
+}
+
+// ListFilter - фильтр по списку значений колонки, например ?type=smoke,molotov&map=!dust2
+type ListFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// ParseListFilter разбирает значения через запятую, значения с префиксом "!" исключаются
+func ParseListFilter(s string) ListFilter {
+	var f ListFilter
+
+	for _, value := range strings.Split(s, ",") {
+		value = strings.TrimSpace(value)
+
+		switch {
+		case value == "" || value == "!":
+			continue
+		case strings.HasPrefix(value, "!"):
+			f.Exclude = append(f.Exclude, strings.TrimPrefix(value, "!"))
+		default:
+			f.Include = append(f.Include, value)
+		}
+	}
+
+	return f
+}
+
+func ValidateListFilter(v *validator.Validator, key string, f ListFilter, safeList []string) {
+	v.Check(len(f.Include)+len(f.Exclude) <= 20, key, "must contain a maximum of 20 values")
+
+	if safeList == nil {
+		return
+	}
+
+	for _, values := range [][]string{f.Include, f.Exclude} {
+		for _, value := range values {
+			v.Check(v.In(value, safeList), key, "invalid value "+value)
+		}
+	}
+}
+
+// condition возвращает условия WHERE для колонки, column берется только из кода
+func (f ListFilter) condition(column string, args *queryArgs) []string {
+	var conditions []string
+
+	if len(f.Include) > 0 {
+		conditions = append(conditions, fmt.Sprintf("%s = ANY(%s)", column, args.add(pq.Array(f.Include))))
+	}
+
+	if len(f.Exclude) > 0 {
+		conditions = append(conditions, fmt.Sprintf("%s <> ALL(%s)", column, args.add(pq.Array(f.Exclude))))
+	}
+
+	return conditions
+}
+
+// queryArgs - аргументы запроса, собираемого из условий, add возвращает плейсхолдер $n
+type queryArgs []interface{}
+
+func (a *queryArgs) add(value interface{}) string {
+	*a = append(*a, value)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+// cursor - позиция последней отданной записи для keyset пагинации,
+// клиенту отдается в виде непрозрачной base64 строки
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int64  `json:"id"`
+}
+
+func encodeCursor(c cursor) string {
+	js, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+// decodeCursor разбирает курсор и проверяет, что он был выдан для той же сортировки
+func decodeCursor(s string, sort string) (cursor, error) {
+	var c cursor
+
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	if err = json.Unmarshal(js, &c); err != nil || c.Sort != sort {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+func ValidateFilters(v *validator.Validator, f Filters) {
+	v.Check(f.Page > 0, "page", "must be greater than zero")
+	v.Check(f.Page <= 10_000_000, "page", "must be a maximum of 10 million")
+
+	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
+	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
+
+	v.Check(v.In(f.Sort, f.SortSafeList), "sort", "invalid sort value")
+
+	if f.Cursor != "" {
+		_, err := decodeCursor(f.Cursor, f.Sort)
+		v.Check(err == nil, "cursor", "invalid cursor value")
+	}
 }
 
 func (f Filters) sortColumn() string {
@@ -22,3 +154,35 @@ func (f Filters) sortDirection() string {
 	}
 	return "ASC"
 }
+
+// keysetOperator возвращает оператор сравнения строк (sort column, id) для keyset пагинации
+func (f Filters) keysetOperator() string {
+	if f.sortDirection() == "DESC" {
+		return "<"
+	}
+	return ">"
+}
+
+func (f Filters) limit() int {
+	return f.PageSize
+}
+
+func (f Filters) offset() int {
+	return (f.Page - 1) * f.PageSize
+}
+
+// calculateMetadata считает метаданные пагинации по общему кол-ву записей,
+// если записей нет - возвращаем пустую структуру
+func calculateMetadata(totalRecords, page, pageSize int) Metadata {
+	if totalRecords == 0 {
+		return Metadata{}
+	}
+
+	return Metadata{
+		CurrentPage:  page,
+		PageSize:     pageSize,
+		FirstPage:    1,
+		LastPage:     int(math.Ceil(float64(totalRecords) / float64(pageSize))),
+		TotalRecords: totalRecords,
+	}
+}
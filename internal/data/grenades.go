@@ -5,20 +5,295 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
 )
 
 type Grenade struct {
-	ID          int64    `json:"id"`
-	Map         string   `json:"map"`
-	Title       string   `json:"title"`
-	Description string   `json:"description"`
-	Type        string   `json:"type"`
-	Side        string   `json:"side"`
-	Version     int32    `json:"version"`
-	Images      []*Image `json:"images,omitempty"`
+	ID          int64  `json:"id"`
+	Map         string `json:"map"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	Side        string `json:"side"`
+	TargetID    int64  `json:"target_id,omitempty"`
+	// VerificationStatus - работает ли раскидка на текущей версии карты
+	VerificationStatus string     `json:"verification_status"`
+	VerifiedBuild      string     `json:"verified_build,omitempty"`
+	VerifiedAt         *time.Time `json:"verified_at,omitempty"`
+	// CreatedBy и UpdatedBy - id автора и последнего редактора, 0 если пользователь удален
+	CreatedBy int64 `json:"created_by,omitempty"`
+	UpdatedBy int64 `json:"updated_by,omitempty"`
+	// Credit - кто нашел раскидку, например про игрок
+	Credit string `json:"credit,omitempty"`
+	// Status - этап публикации, публично видны только published
+	Status        string     `json:"status"`
+	ReviewComment string     `json:"review_comment,omitempty"`
+	ReviewedBy    int64      `json:"reviewed_by,omitempty"`
+	ReviewedAt    *time.Time `json:"reviewed_at,omitempty"`
+	// DeletedAt - время удаления в корзину, удаленные раскидки видны только в корзине
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy int64      `json:"deleted_by,omitempty"`
+	// OpenReports - кол-во открытых жалоб игроков на раскидку
+	OpenReports int       `json:"open_reports"`
+	CreatedAt   time.Time `json:"created_at"`
+	Version     int32     `json:"version"`
+	Images      []*Image  `json:"images,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	// VariantCount - кол-во вариантов цели, заполняется только при сворачивании списка по целям
+	VariantCount int               `json:"variant_count,omitempty"`
+	Variants     []*Grenade        `json:"variants,omitempty"`
+	Relations    []*RelatedGrenade `json:"relations,omitempty"`
+}
+
+// grenadeColumns - колонки гранаты в порядке Grenade.fields(), таблица должна называться grenades
+const grenadeColumns = `grenades.id, grenades.map, grenades.title, grenades.description,
+	grenades.type, grenades.side, COALESCE(grenades.target_id, 0), grenades.verification_status,
+	grenades.verified_build, grenades.verified_at, COALESCE(grenades.created_by, 0),
+	COALESCE(grenades.updated_by, 0), grenades.credit, grenades.status, grenades.review_comment,
+	COALESCE(grenades.reviewed_by, 0), grenades.reviewed_at, grenades.deleted_at, COALESCE(grenades.deleted_by, 0),
+	grenades.created_at, grenades.version,
+	(SELECT count(*) FROM grenade_reports
+		WHERE grenade_reports.grenade_id = grenades.id AND grenade_reports.status = 'open')`
+
+func (g *Grenade) fields() []interface{} {
+	return []interface{}{
+		&g.ID,
+		&g.Map,
+		&g.Title,
+		&g.Description,
+		&g.Type,
+		&g.Side,
+		&g.TargetID,
+		&g.VerificationStatus,
+		&g.VerifiedBuild,
+		&g.VerifiedAt,
+		&g.CreatedBy,
+		&g.UpdatedBy,
+		&g.Credit,
+		&g.Status,
+		&g.ReviewComment,
+		&g.ReviewedBy,
+		&g.ReviewedAt,
+		&g.DeletedAt,
+		&g.DeletedBy,
+		&g.CreatedAt,
+		&g.Version,
+		&g.OpenReports,
+	}
+}
+
+// listFields - поля для выборки списка, где после grenadeColumns идет variant_count
+func (g *Grenade) listFields() []interface{} {
+	return append(g.fields(), &g.VariantCount)
+}
+
+// GrenadeSearch - условия выборки списка гранат
+type GrenadeSearch struct {
+	Maps          ListFilter
+	Sides         ListFilter
+	Types         ListFilter
+	Verifications ListFilter
+	Statuses      ListFilter
+	// AuthorID - только гранаты, созданные пользователем, 0 не фильтрует
+	AuthorID int64
+	Q        string
+	Filter   *FilterQuery
+	// Tags - гранаты хотя бы с одним из тегов, или со всеми, если TagsMatchAll
+	Tags         []string
+	TagsMatchAll bool
+	// CollapseTargets - вместо всех вариантов цели возвращать один с кол-вом вариантов
+	CollapseTargets bool
+	// Deleted - выборка из корзины вместо обычного списка
+	Deleted bool
+}
+
+// grenadeFilterFields - поля, доступные в языке запросов filter
+var grenadeFilterFields = map[string]filterField{
+	"map":          equalFilterField("map"),
+	"side":         equalFilterField("side"),
+	"type":         equalFilterField("type"),
+	"verification": equalFilterField("verification_status"),
+	"title": func(value string, args *queryArgs) string {
+		return "title ILIKE " + args.add("%"+escapeLike(value)+"%")
+	},
+	"text": func(value string, args *queryArgs) string {
+		return "search @@ " + searchQuery(args.add(value))
+	},
+	"tag": func(value string, args *queryArgs) string {
+		return tagsCondition([]string{value}, false, args)
+	},
+	// техника броска (jumpthrow, runthrow...) хранится тегом
+	"technique": func(value string, args *queryArgs) string {
+		return tagsCondition([]string{value}, false, args)
+	},
+}
+
+// tagsCondition - условие на теги гранаты, при matchAll должны быть все теги из names
+func tagsCondition(names []string, matchAll bool, args *queryArgs) string {
+	if matchAll {
+		return fmt.Sprintf(`(
+		SELECT count(DISTINCT t.name) FROM grenades_tags gt
+		INNER JOIN tags t ON t.id = gt.tag_id
+		WHERE gt.grenade_id = grenades.id AND t.name = ANY(%[1]s)) = cardinality(%[1]s::text[])`, args.add(pq.Array(uniqueStrings(names))))
+	}
+
+	return fmt.Sprintf(`EXISTS (
+		SELECT 1 FROM grenades_tags gt
+		INNER JOIN tags t ON t.id = gt.tag_id
+		WHERE gt.grenade_id = grenades.id AND t.name = ANY(%s))`, args.add(pq.Array(names)))
+}
+
+func equalFilterField(column string) filterField {
+	return func(value string, args *queryArgs) string {
+		return column + " = " + args.add(value)
+	}
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// where собирает условие WHERE, добавляя значения в args
+func (s GrenadeSearch) where(args *queryArgs) (string, error) {
+	conditions := []string{"deleted_at IS NULL"}
+	if s.Deleted {
+		conditions[0] = "deleted_at IS NOT NULL"
+	}
+
+	conditions = append(conditions, s.Maps.condition("map", args)...)
+	conditions = append(conditions, s.Sides.condition("side", args)...)
+	conditions = append(conditions, s.Types.condition("type", args)...)
+	conditions = append(conditions, s.Verifications.condition("verification_status", args)...)
+	conditions = append(conditions, s.Statuses.condition("status", args)...)
+
+	if s.AuthorID > 0 {
+		conditions = append(conditions, "created_by = "+args.add(s.AuthorID))
+	}
+
+	if s.Q != "" {
+		conditions = append(conditions, "search @@ "+searchQuery(args.add(s.Q)))
+	}
+
+	if len(s.Tags) > 0 {
+		conditions = append(conditions, tagsCondition(s.Tags, s.TagsMatchAll, args))
+	}
+
+	if s.Filter != nil {
+		filter, err := s.Filter.compile(grenadeFilterFields, args)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, filter)
+	}
+
+	return strings.Join(conditions, " AND "), nil
+}
+
+// from возвращает источник выборки списка с условием WHERE, к которому можно добавлять условия через AND.
+// При CollapseTargets от каждой цели остается вариант с наименьшим id
+func (s GrenadeSearch) from(args *queryArgs) (string, error) {
+	where, err := s.where(args)
+	if err != nil {
+		return "", err
+	}
+
+	if s.CollapseTargets {
+		return fmt.Sprintf(`(
+		SELECT grenades.*, count(*) OVER w AS variant_count, row_number() OVER (w ORDER BY id) AS variant_rank
+		FROM grenades
+		WHERE %s
+		WINDOW w AS (PARTITION BY COALESCE(target_id, -id))) grenades
+	WHERE variant_rank = 1`, where), nil
+	}
+
+	return fmt.Sprintf(`(
+		SELECT grenades.*, 0 AS variant_count
+		FROM grenades
+		WHERE %s) grenades
+	WHERE TRUE`, where), nil
+}
+
+// sortValue возвращает значение колонки сортировки для курсора
+func (g *Grenade) sortValue(column string) string {
+	switch column {
+	case "map":
+		return g.Map
+	case "side":
+		return g.Side
+	case "type":
+		return g.Type
+	case "title":
+		return g.Title
+	case "created_at":
+		return g.CreatedAt.Format(time.RFC3339Nano)
+	case "deleted_at":
+		if g.DeletedAt != nil {
+			return g.DeletedAt.Format(time.RFC3339Nano)
+		}
+		return ""
+	default:
+		return strconv.FormatInt(g.ID, 10)
+	}
+}
+
+// searchQuery - поисковый запрос по плейсхолдеру param, контент на русском и английском,
+// поэтому объединяем запросы с обоими стеммерами
+func searchQuery(param string) string {
+	return fmt.Sprintf("(websearch_to_tsquery('russian', %[1]s) || websearch_to_tsquery('english', %[1]s))", param)
+}
+
+var (
+	GrenadeTypes = []string{"smoke", "molotov", "incendiary", "he", "flash", "decoy"}
+	GrenadeSides = []string{"CT", "T"}
+	// VerificationStatuses - unverified ставится новым раскидкам и после обновления карты
+	VerificationStatuses = []string{"verified", "unverified", "broken"}
+	// GrenadeStatuses - этапы публикации: черновик автора, на проверке, опубликована, отклонена модератором
+	GrenadeStatuses = []string{"draft", "pending_review", "published", "rejected"}
+)
+
+// grenadeStatusTransitions - из каких статусов можно перейти в статус.
+// Отклонить можно и опубликованную раскидку, тогда она снимается с публикации
+var grenadeStatusTransitions = map[string][]string{
+	"pending_review": {"draft", "rejected"},
+	"published":      {"pending_review"},
+	"rejected":       {"pending_review", "published"},
+}
+
+// ValidateStatusTransition проверяет смену статуса и комментарий модератора,
+// при отклонении комментарий обязателен, чтобы автор знал что исправить
+func ValidateStatusTransition(v *validator.Validator, grenade *Grenade, status string, comment string) {
+	v.Check(v.In(grenade.Status, grenadeStatusTransitions[status]), "status", fmt.Sprintf("cannot change from %s to %s", grenade.Status, status))
+	v.Check(status != "rejected" || comment != "", "comment", "must be provided")
+	v.Check(len(comment) <= 1000, "comment", "must not be grater than 1000 bytes")
+}
+
+// EditableByAuthor - автор без права grenades:write может менять только черновики и отклоненные раскидки
+func (g *Grenade) EditableByAuthor() bool {
+	return g.Status == "draft" || g.Status == "rejected"
+}
+
+// grenadeRules - правила согласованности полей гранаты,
+// в CS2 молотов покупает только T, а зажигательную - только CT
+var grenadeRules = []validator.Rule[*Grenade]{
+	{
+		Key:     "type",
+		Message: "molotov is only available for side T, use incendiary for CT",
+		Check: func(g *Grenade) bool {
+			return g.Type != "molotov" || g.Side == "T"
+		},
+	},
+	{
+		Key:     "type",
+		Message: "incendiary is only available for side CT, use molotov for T",
+		Check: func(g *Grenade) bool {
+			return g.Type != "incendiary" || g.Side == "CT"
+		},
+	},
 }
 
 type GrenadeModel struct {
@@ -35,32 +310,32 @@ func ValidateGrenade(grenade *Grenade, v *validator.Validator) {
 	v.Check(len(grenade.Description) <= 700, "description", "must not be grater than 700 bytes")
 
 	v.Check(grenade.Type != "", "type", "must be provided")
-	v.Check(v.In(grenade.Type, []string{"smoke", "molotov", "he", "flash", "decoy"}), "type", "value of type must be smoke|molotov|he|flash|decoy")
+	v.Check(v.In(grenade.Type, GrenadeTypes), "type", "value of type must be smoke|molotov|incendiary|he|flash|decoy")
 
 	v.Check(grenade.Side != "", "side", "must be provided")
-	v.Check(v.In(grenade.Side, []string{"CT", "T"}), "side", "value of side must be T or CT")
+	v.Check(v.In(grenade.Side, GrenadeSides), "side", "value of side must be T or CT")
+
+	v.Check(grenade.TargetID >= 0, "target_id", "must not be negative")
+
+	v.Check(len(grenade.Credit) <= 200, "credit", "must not be grater than 200 bytes")
+
+	if v.Valid() {
+		validator.Apply(v, grenade, grenadeRules)
+	}
 }
 
 func (m GrenadeModel) Get(id int64) (*Grenade, error) {
-	query := `
-	SELECT id, map, title, description, type, side, version
+	query := fmt.Sprintf(`
+	SELECT %s
 	FROM grenades
-	WHERE id = $1`
+	WHERE id = $1 AND deleted_at IS NULL`, grenadeColumns)
 
 	var grenade Grenade
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
-		&grenade.ID,
-		&grenade.Map,
-		&grenade.Title,
-		&grenade.Description,
-		&grenade.Type,
-		&grenade.Side,
-		&grenade.Version,
-	)
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(grenade.fields()...)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -73,25 +348,63 @@ func (m GrenadeModel) Get(id int64) (*Grenade, error) {
 	return &grenade, nil
 }
 
+// Insert сохраняет раскидку вместе с тегами grenade.Tags и первой ревизией
 func (m GrenadeModel) Insert(grenade *Grenade) error {
 	query := `
-	INSERT INTO grenades (map, title, description, type, side)
-	VALUES ($1, $2, $3, $4, $5)
-	RETURNING id, version`
+	INSERT INTO grenades (map, title, description, type, side, target_id, created_by, updated_by, credit)
+	VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), NULLIF($7, 0), NULLIF($7, 0), $8)
+	RETURNING id, verification_status, status, created_at, version`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []interface{}{grenade.Map, grenade.Title, grenade.Description, grenade.Type, grenade.Side}
+	grenade.UpdatedBy = grenade.CreatedBy
 
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&grenade.ID, &grenade.Version)
+	args := []interface{}{
+		grenade.Map,
+		grenade.Title,
+		grenade.Description,
+		grenade.Type,
+		grenade.Side,
+		grenade.TargetID,
+		grenade.CreatedBy,
+		grenade.Credit,
+	}
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&grenade.ID, &grenade.VerificationStatus, &grenade.Status, &grenade.CreatedAt, &grenade.Version)
+	if err != nil {
+		return err
+	}
+
+	if len(grenade.Tags) > 0 {
+		err = replaceGrenadeTags(ctx, tx, grenade.ID, grenade.Tags)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = insertGrenadeRevision(ctx, tx, grenade.ID, grenade.CreatedBy, "")
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (m GrenadeModel) Update(grenade *Grenade) error {
+// Update сохраняет изменения и ревизию новой версии, note - необязательное описание правки.
+// tags заменяют теги гранаты в той же транзакции, nil оставляет теги без изменений
+func (m GrenadeModel) Update(grenade *Grenade, note string, tags []string) error {
 	query := `
 	UPDATE grenades 
-	SET map=$1, title=$2, description=$3, type=$4, side=$5, version=version + 1
-	WHERE id=$6 AND version=$7
+	SET map=$1, title=$2, description=$3, type=$4, side=$5, target_id=NULLIF($6, 0),
+		updated_by=NULLIF($7, 0), credit=$8, version=version + 1
+	WHERE id=$9 AND version=$10
 	RETURNING version`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -103,11 +416,20 @@ func (m GrenadeModel) Update(grenade *Grenade) error {
 		grenade.Description,
 		grenade.Type,
 		grenade.Side,
+		grenade.TargetID,
+		grenade.UpdatedBy,
+		grenade.Credit,
 		grenade.ID,
 		grenade.Version,
 	}
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&grenade.Version)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&grenade.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -117,74 +439,499 @@ func (m GrenadeModel) Update(grenade *Grenade) error {
 		}
 	}
 
+	if tags != nil {
+		err = replaceGrenadeTags(ctx, tx, grenade.ID, tags)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = insertGrenadeRevision(ctx, tx, grenade.ID, grenade.UpdatedBy, note)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	if tags != nil {
+		grenade.Tags = tags
+	}
+
 	return nil
 }
 
-func (m GrenadeModel) Delete(id int64) error {
+// Delete переносит раскидку в корзину, изображения и связи остаются до очистки корзины
+func (m GrenadeModel) Delete(grenade *Grenade, userID int64) error {
 	query := `
-	DELETE FROM grenades
-	WHERE id = $1`
+	UPDATE grenades
+	SET deleted_at = NOW(), deleted_by = NULLIF($3, 0), version = version + 1
+	WHERE id = $1 AND version = $2 AND deleted_at IS NULL
+	RETURNING deleted_at, COALESCE(deleted_by, 0), version`
+
+	args := []interface{}{grenade.ID, grenade.Version, userID}
 
+	return m.changeDeleted(grenade, query, args, userID, "deleted")
+}
+
+// Restore возвращает раскидку из корзины
+func (m GrenadeModel) Restore(grenade *Grenade, userID int64) error {
+	query := `
+	UPDATE grenades
+	SET deleted_at = NULL, deleted_by = NULL, version = version + 1
+	WHERE id = $1 AND version = $2 AND deleted_at IS NOT NULL
+	RETURNING deleted_at, COALESCE(deleted_by, 0), version`
+
+	args := []interface{}{grenade.ID, grenade.Version}
+
+	return m.changeDeleted(grenade, query, args, userID, "restored")
+}
+
+// changeDeleted выполняет перенос в корзину или восстановление и пишет ревизию
+func (m GrenadeModel) changeDeleted(grenade *Grenade, query string, args []interface{}, userID int64, note string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&grenade.DeletedAt, &grenade.DeletedBy, &grenade.Version)
 	if err != nil {
-		return err
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
 	}
 
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
+	err = insertGrenadeRevision(ctx, tx, grenade.ID, userID, note)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
 
-func (m GrenadeModel) GetAll(csMap string, side string, grenType string, filters Filters) ([]*Grenade, error) {
+// GetDeleted возвращает раскидку из корзины
+func (m GrenadeModel) GetDeleted(id int64) (*Grenade, error) {
 	query := fmt.Sprintf(`
-	SELECT id, map, title, description, type, side, version
+	SELECT %s
 	FROM grenades
-	WHERE (map = $1 OR $1 = '') AND (side = $2 OR $2 = '') AND (type = $3 OR $3 = '')
-	ORDER BY %s %s, id ASC`, filters.sortColumn(), filters.sortDirection())
+	WHERE id = $1 AND deleted_at IS NOT NULL`, grenadeColumns)
+
+	var grenade Grenade
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query, csMap, side, grenType)
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(grenade.fields()...)
 	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &grenade, nil
+}
+
+// GetDeletedBefore возвращает id раскидок, удаленных в корзину раньше before, не больше limit
+func (m GrenadeModel) GetDeletedBefore(before time.Time, limit int) ([]int64, error) {
+	query := `
+	SELECT id
+	FROM grenades
+	WHERE deleted_at < $1
+	ORDER BY deleted_at
+	LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+
+	for rows.Next() {
+		var id int64
+
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
 		return nil, err
 	}
+
+	return ids, nil
+}
+
+// Purge окончательно удаляет раскидки из корзины вместе с изображениями, связями и ревизиями.
+// Объекты изображений в хранилище нужно удалить до вызова
+func (m GrenadeModel) Purge(ids []int64) (int64, error) {
+	query := `
+	DELETE FROM grenades
+	WHERE id = ANY($1) AND deleted_at IS NOT NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (m GrenadeModel) GetAll(search GrenadeSearch, filters Filters) ([]*Grenade, Metadata, error) {
+	args := queryArgs{}
+	from, err := search.from(&args)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	// при поиске по тексту сначала сортируем по релевантности, sort используется как вторичная сортировка
+	rank := ""
+	if search.Q != "" {
+		rank = "ts_rank(search, " + searchQuery(args.add(search.Q)) + ") DESC, "
+	}
+
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), %s, variant_count
+	FROM %s
+	ORDER BY %s%s %s, id ASC
+	LIMIT %s OFFSET %s`, grenadeColumns, from, rank, filters.sortColumn(), filters.sortDirection(), args.add(filters.limit()), args.add(filters.offset()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
 	defer rows.Close()
 
+	totalRecords := 0
 	grenades := []*Grenade{}
 
 	for rows.Next() {
 		var grenade Grenade
 
-		err := rows.Scan(
-			&grenade.ID,
-			&grenade.Map,
-			&grenade.Title,
-			&grenade.Description,
-			&grenade.Type,
-			&grenade.Side,
-			&grenade.Version,
-		)
+		err := rows.Scan(append([]interface{}{&totalRecords}, grenade.listFields()...)...)
 		if err != nil {
-			return nil, err
+			return nil, Metadata{}, err
 		}
 
 		grenades = append(grenades, &grenade)
 	}
 
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return grenades, metadata, nil
+}
+
+// GetAllByCursor - keyset пагинация по (sort column, id), в отличие от LIMIT/OFFSET
+// не дает дублей и пропусков при добавлении новых записей между запросами.
+// Поиск q здесь только фильтрует, сортировки по релевантности в keyset режиме нет
+func (m GrenadeModel) GetAllByCursor(search GrenadeSearch, filters Filters) ([]*Grenade, Metadata, error) {
+	column := filters.sortColumn()
+	direction := filters.sortDirection()
+
+	args := queryArgs{}
+	from, err := search.from(&args)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	if filters.Cursor != "" {
+		c, err := decodeCursor(filters.Cursor, filters.Sort)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		from += fmt.Sprintf(" AND (%s, id) %s (%s, %s)", column, filters.keysetOperator(), args.add(c.Value), args.add(c.ID))
+	}
+
+	query := fmt.Sprintf(`
+	SELECT %s, variant_count
+	FROM %s
+	ORDER BY %s %s, id %s
+	LIMIT %s`, grenadeColumns, from, column, direction, direction, args.add(filters.limit()+1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	grenades := []*Grenade{}
+
+	for rows.Next() {
+		var grenade Grenade
+
+		err := rows.Scan(grenade.listFields()...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		grenades = append(grenades, &grenade)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := Metadata{PageSize: filters.PageSize}
+
+	// запрашиваем на одну запись больше, чтобы понять есть ли следующая страница
+	if len(grenades) > filters.PageSize {
+		grenades = grenades[:filters.PageSize]
+		last := grenades[len(grenades)-1]
+		metadata.NextCursor = encodeCursor(cursor{
+			Sort:  filters.Sort,
+			Value: last.sortValue(column),
+			ID:    last.ID,
+		})
+	}
+
+	return grenades, metadata, nil
+}
+
+// GetByIDs возвращает гранаты по списку id в любом статусе, отсутствующие id пропускаются.
+// Неопубликованные раскидки нужно скрыть от тех, кому они не видны
+func (m GrenadeModel) GetByIDs(ids []int64) (map[int64]*Grenade, error) {
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM grenades
+	WHERE id = ANY($1) AND deleted_at IS NULL`, grenadeColumns)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grenades := make(map[int64]*Grenade)
+
+	for rows.Next() {
+		var grenade Grenade
+
+		err := rows.Scan(grenade.fields()...)
+		if err != nil {
+			return nil, err
+		}
+
+		grenades[grenade.ID] = &grenade
+	}
+
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
 	return grenades, nil
 }
+
+// GetByTargetID возвращает все варианты цели
+func (m GrenadeModel) GetByTargetID(targetID int64) ([]*Grenade, error) {
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM grenades
+	WHERE target_id = $1 AND deleted_at IS NULL
+	ORDER BY id`, grenadeColumns)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, targetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grenades := []*Grenade{}
+
+	for rows.Next() {
+		var variant Grenade
+
+		err := rows.Scan(variant.fields()...)
+		if err != nil {
+			return nil, err
+		}
+
+		grenades = append(grenades, &variant)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return grenades, nil
+}
+
+func ValidateVerification(status string, build string, v *validator.Validator) {
+	v.Check(v.In(status, VerificationStatuses), "status", "value of status must be verified|unverified|broken")
+	v.Check(status != "verified" || build != "", "build", "must be provided for verified status")
+	v.Check(len(build) <= 50, "build", "must not be grater than 50 bytes")
+}
+
+// SetVerification меняет статус проверки раскидки, build - версия CS2 или карты,
+// на которой раскидку проверили, editorID - кто проверил
+func (m GrenadeModel) SetVerification(grenade *Grenade, status string, build string, editorID int64) error {
+	query := `
+	UPDATE grenades
+	SET verification_status = $1,
+		verified_build = CASE WHEN $1 = 'verified' THEN $2 ELSE verified_build END,
+		verified_at = CASE WHEN $1 = 'verified' THEN NOW() ELSE verified_at END,
+		version = version + 1
+	WHERE id = $3 AND version = $4
+	RETURNING verification_status, verified_build, verified_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{status, build, grenade.ID, grenade.Version}
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(
+		&grenade.VerificationStatus,
+		&grenade.VerifiedBuild,
+		&grenade.VerifiedAt,
+		&grenade.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = insertGrenadeRevision(ctx, tx, grenade.ID, editorID, "verification: "+status)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetStatus меняет статус публикации, userID - автор при отправке на проверку или модератор.
+// При отправке на проверку прошлый отзыв модератора сохраняется
+func (m GrenadeModel) SetStatus(grenade *Grenade, status string, comment string, userID int64) error {
+	query := `
+	UPDATE grenades
+	SET status = $1,
+		review_comment = CASE WHEN $1 = 'pending_review' THEN review_comment ELSE $2 END,
+		reviewed_by = CASE WHEN $1 = 'pending_review' THEN reviewed_by ELSE NULLIF($3, 0) END,
+		reviewed_at = CASE WHEN $1 = 'pending_review' THEN reviewed_at ELSE NOW() END,
+		version = version + 1
+	WHERE id = $4 AND version = $5
+	RETURNING status, review_comment, COALESCE(reviewed_by, 0), reviewed_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{status, comment, userID, grenade.ID, grenade.Version}
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(
+		&grenade.Status,
+		&grenade.ReviewComment,
+		&grenade.ReviewedBy,
+		&grenade.ReviewedAt,
+		&grenade.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = insertGrenadeRevision(ctx, tx, grenade.ID, userID, "status: "+status)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MarkMapUpdated помечает все проверенные раскидки карты как требующие перепроверки,
+// сломанные остаются сломанными. Возвращает id помеченных раскидок
+func (m GrenadeModel) MarkMapUpdated(csMap string, editorID int64) ([]int64, error) {
+	// ревизии помеченных раскидок пишутся тем же запросом
+	query := `
+	WITH updated AS (
+		UPDATE grenades
+		SET verification_status = 'unverified', version = version + 1
+		WHERE map = $1 AND verification_status = 'verified' AND deleted_at IS NULL
+		RETURNING grenades.*
+	)
+	INSERT INTO grenade_revisions (grenade_id, version, data, editor_id, note)
+	SELECT id, version, to_jsonb(updated) - 'search', NULLIF($2, 0), 'map updated'
+	FROM updated
+	RETURNING grenade_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, csMap, editorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+
+	for rows.Next() {
+		var id int64
+
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
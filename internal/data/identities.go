@@ -0,0 +1,255 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"time"
+)
+
+var ErrDuplicateIdentity = errors.New("duplicate identity")
+
+// UserIdentity - аккаунт у внешнего провайдера (oidc, steam), привязанный к пользователю
+type UserIdentity struct {
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	UserID    int64     `json:"user_id"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UserIdentityModel struct {
+	DB *sql.DB
+}
+
+// GetUser возвращает пользователя, к которому привязан аккаунт провайдера
+func (m UserIdentityModel) GetUser(provider, subject string) (*User, error) {
+	query := `
+	SELECT ` + userColumns + `
+	FROM users
+	INNER JOIN user_identities ON user_identities.user_id = users.id
+	WHERE user_identities.provider = $1 AND user_identities.subject = $2`
+
+	var user User
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, provider, subject).Scan(user.fields()...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+func (m UserIdentityModel) GetAllForUser(userID int64) ([]*UserIdentity, error) {
+	query := `
+	SELECT provider, subject, user_id, email, created_at
+	FROM user_identities
+	WHERE user_id = $1
+	ORDER BY provider`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []*UserIdentity{}
+
+	for rows.Next() {
+		var identity UserIdentity
+
+		err := rows.Scan(&identity.Provider, &identity.Subject, &identity.UserID, &identity.Email, &identity.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		identities = append(identities, &identity)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return identities, nil
+}
+
+// Insert привязывает аккаунт провайдера, у пользователя может быть один аккаунт каждого провайдера
+func (m UserIdentityModel) Insert(identity *UserIdentity) error {
+	query := `
+	INSERT INTO user_identities (provider, subject, user_id, email)
+	VALUES ($1, $2, $3, $4)
+	RETURNING created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{identity.Provider, identity.Subject, identity.UserID, identity.Email}
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&identity.CreatedAt)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicateIdentity
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// InsertWithUser создает пользователя без пароля вместе с привязкой аккаунта провайдера
+func (m UserIdentityModel) InsertWithUser(user *User, identity *UserIdentity) error {
+	userQuery := `
+	INSERT INTO users (name, email, password_hash)
+	VALUES ($1, NULLIF($2, ''), '')
+	RETURNING id, created_at, role, version`
+
+	identityQuery := `
+	INSERT INTO user_identities (provider, subject, user_id, email)
+	VALUES ($1, $2, $3, $4)
+	RETURNING created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, userQuery, user.Name, user.Email).Scan(&user.ID, &user.CreatedAt, &user.Role, &user.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicateEmail
+		default:
+			return err
+		}
+	}
+
+	identity.UserID = user.ID
+
+	args := []interface{}{identity.Provider, identity.Subject, identity.UserID, identity.Email}
+
+	err = tx.QueryRowContext(ctx, identityQuery, args...).Scan(&identity.CreatedAt)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicateIdentity
+		default:
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// OAuthState - state и nonce одного входа через провайдера, UserID != 0 - привязка к вошедшему пользователю
+type OAuthState struct {
+	Plaintext string
+	Provider  string
+	Nonce     string
+	UserID    int64
+	Expiry    time.Time
+}
+
+type OAuthStateModel struct {
+	DB *sql.DB
+}
+
+func randomString() (string, error) {
+	randomBytes := make([]byte, 16)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes), nil
+}
+
+// New создает state для входа, в БД хранится только hash
+func (m OAuthStateModel) New(provider string, userID int64, ttl time.Duration) (*OAuthState, error) {
+	var err error
+
+	state := &OAuthState{
+		Provider: provider,
+		UserID:   userID,
+		Expiry:   time.Now().Add(ttl),
+	}
+
+	if state.Plaintext, err = randomString(); err != nil {
+		return nil, err
+	}
+
+	if state.Nonce, err = randomString(); err != nil {
+		return nil, err
+	}
+
+	query := `
+	INSERT INTO oauth_states (hash, provider, nonce, user_id, expiry)
+	VALUES ($1, $2, $3, NULLIF($4, 0), $5)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// заодно удаляем states незавершенных входов
+	_, err = m.DB.ExecContext(ctx, "DELETE FROM oauth_states WHERE expiry < NOW()")
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256([]byte(state.Plaintext))
+
+	args := []interface{}{hash[:], state.Provider, state.Nonce, state.UserID, state.Expiry}
+
+	_, err = m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// Consume удаляет state и возвращает его, state можно использовать только один раз
+func (m OAuthStateModel) Consume(provider, plaintext string) (*OAuthState, error) {
+	query := `
+	DELETE FROM oauth_states
+	WHERE hash = $1 AND provider = $2 AND expiry > NOW()
+	RETURNING provider, nonce, COALESCE(user_id, 0), expiry`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	hash := sha256.Sum256([]byte(plaintext))
+
+	state := &OAuthState{Plaintext: plaintext}
+
+	err := m.DB.QueryRowContext(ctx, query, hash[:], provider).Scan(&state.Provider, &state.Nonce, &state.UserID, &state.Expiry)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return state, nil
+}
@@ -7,14 +7,18 @@ import (
 	"mime/multipart"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
 )
 
 type Image struct {
 	ID        int64  `json:"id,omitempty"`
 	Name      string `json:"name"`
-	GrenadeID int64  `json:"-"`
+	GrenadeID int64  `json:"grenade_id,omitempty"`
 	ImageURL  string `json:"image_url"`
+	// DeletedAt и DeletedBy заполняются только для изображений в корзине
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy int64      `json:"deleted_by,omitempty"`
 }
 
 type ImageModel struct {
@@ -28,8 +32,8 @@ func VlidateImage(fileHeader *multipart.FileHeader, v *validator.Validator) {
 
 func (m ImageModel) Get(id int64) (*Image, error) {
 	query := `
-	SELECT id, name FROM images
-	WHERE id = $1`
+	SELECT id, name, grenade_id FROM images
+	WHERE id = $1 AND deleted_at IS NULL`
 
 	var image Image
 
@@ -39,6 +43,7 @@ func (m ImageModel) Get(id int64) (*Image, error) {
 	err := m.DB.QueryRowContext(ctx, query, id).Scan(
 		&image.ID,
 		&image.Name,
+		&image.GrenadeID,
 	)
 	if err != nil {
 		switch {
@@ -67,7 +72,7 @@ func (m ImageModel) Insert(image *Image) error {
 func (m ImageModel) GetByGrenadeID(grenadeId int64) ([]*Image, error) {
 	query := `
 	SELECT id, name FROM images
-	WHERE grenade_id=$1`
+	WHERE grenade_id=$1 AND deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -105,7 +110,8 @@ func (m ImageModel) GetByGrenadeID(grenadeId int64) ([]*Image, error) {
 func (m ImageModel) GetAll() ([]*Image, error) {
 	query := `
 	SELECT name, grenade_id
-	FROM images`
+	FROM images
+	WHERE deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -139,15 +145,32 @@ func (m ImageModel) GetAll() ([]*Image, error) {
 	return images, nil
 }
 
-func (m ImageModel) Delete(id int64) error {
+// Delete переносит изображение в корзину, объект в хранилище удаляется только при очистке корзины
+func (m ImageModel) Delete(id int64, userID int64) error {
 	query := `
-	DELETE FROM images
-	WHERE id = $1`
+	UPDATE images
+	SET deleted_at = NOW(), deleted_by = NULLIF($2, 0)
+	WHERE id = $1 AND deleted_at IS NULL`
+
+	return m.exec(query, id, userID)
+}
+
+// Restore возвращает изображение из корзины
+func (m ImageModel) Restore(id int64) error {
+	query := `
+	UPDATE images
+	SET deleted_at = NULL, deleted_by = NULL
+	WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	return m.exec(query, id)
+}
 
+// exec выполняет изменение одного изображения, ErrRecordNotFound - если ничего не изменилось
+func (m ImageModel) exec(query string, args ...interface{}) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
+	result, err := m.DB.ExecContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -163,3 +186,139 @@ func (m ImageModel) Delete(id int64) error {
 
 	return nil
 }
+
+// GetDeleted возвращает изображение из корзины
+func (m ImageModel) GetDeleted(id int64) (*Image, error) {
+	query := `
+	SELECT id, name, grenade_id, deleted_at, COALESCE(deleted_by, 0)
+	FROM images
+	WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	var image Image
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&image.ID,
+		&image.Name,
+		&image.GrenadeID,
+		&image.DeletedAt,
+		&image.DeletedBy,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &image, nil
+}
+
+// GetTrash возвращает изображения в корзине, сначала удаленные последними.
+// Изображения удаленных раскидок лежат в корзине вместе с раскидкой и здесь не показываются
+func (m ImageModel) GetTrash(filters Filters) ([]*Image, Metadata, error) {
+	query := `
+	SELECT count(*) OVER(), id, name, grenade_id, deleted_at, COALESCE(deleted_by, 0)
+	FROM images
+	WHERE deleted_at IS NOT NULL
+	ORDER BY deleted_at DESC, id DESC
+	LIMIT $1 OFFSET $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	images := []*Image{}
+
+	for rows.Next() {
+		var image Image
+
+		err := rows.Scan(
+			&totalRecords,
+			&image.ID,
+			&image.Name,
+			&image.GrenadeID,
+			&image.DeletedAt,
+			&image.DeletedBy,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		images = append(images, &image)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return images, metadata, nil
+}
+
+// GetForPurge возвращает изображения к окончательному удалению: все изображения раскидок grenadeIDs
+// и изображения, удаленные в корзину раньше before, не больше limit
+func (m ImageModel) GetForPurge(grenadeIDs []int64, before time.Time, limit int) ([]*Image, error) {
+	query := `
+	(SELECT id, name, grenade_id
+	FROM images
+	WHERE grenade_id = ANY($1))
+	UNION
+	(SELECT id, name, grenade_id
+	FROM images
+	WHERE deleted_at < $2
+	ORDER BY deleted_at
+	LIMIT $3)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(grenadeIDs), before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := []*Image{}
+
+	for rows.Next() {
+		var image Image
+
+		err := rows.Scan(&image.ID, &image.Name, &image.GrenadeID)
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, &image)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// Purge окончательно удаляет записи изображений, объекты в хранилище нужно удалить до вызова
+func (m ImageModel) Purge(ids []int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, "DELETE FROM images WHERE id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
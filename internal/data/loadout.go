@@ -0,0 +1,236 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// MaxGrenadesPerPlayer - сколько всего гранат может нести один игрок
+	MaxGrenadesPerPlayer = 4
+	TeamSize             = 5
+)
+
+// GrenadePrice - цена и лимит на игрока для типа гранаты, хранятся в таблице grenade_prices
+type GrenadePrice struct {
+	Type         string `json:"type"`
+	Price        int    `json:"price"`
+	MaxPerPlayer int    `json:"max_per_player"`
+}
+
+type GrenadePriceModel struct {
+	DB *sql.DB
+}
+
+func (m GrenadePriceModel) GetAll() (map[string]*GrenadePrice, error) {
+	query := `
+	SELECT type, price, max_per_player
+	FROM grenade_prices`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := make(map[string]*GrenadePrice)
+
+	for rows.Next() {
+		var price GrenadePrice
+
+		err := rows.Scan(&price.Type, &price.Price, &price.MaxPerPlayer)
+		if err != nil {
+			return nil, err
+		}
+
+		prices[price.Type] = &price
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return prices, nil
+}
+
+// LoadoutItem - граната в плане закупки, PlayerSlot 0 - игрок не назначен
+type LoadoutItem struct {
+	Grenade    *Grenade
+	PlayerSlot int
+}
+
+type LoadoutTypeCost struct {
+	Count int `json:"count"`
+	Price int `json:"price"`
+	Cost  int `json:"cost"`
+}
+
+type PlayerLoadout struct {
+	PlayerSlot int            `json:"player_slot"`
+	GrenadeIDs []int64        `json:"grenade_ids"`
+	Types      map[string]int `json:"types"`
+	Cost       int            `json:"cost"`
+}
+
+type Loadout struct {
+	TotalCost  int                         `json:"total_cost"`
+	ByType     map[string]*LoadoutTypeCost `json:"by_type"`
+	Players    []*PlayerLoadout            `json:"players,omitempty"`
+	Violations []string                    `json:"violations"`
+	Suggested  []*PlayerLoadout            `json:"suggested_split"`
+	Unassigned []int64                     `json:"unassigned,omitempty"`
+}
+
+// PlanLoadout считает стоимость гранат, проверяет лимиты по игрокам из PlayerSlot
+// и предлагает разбивку гранат на команду из TeamSize игроков
+func PlanLoadout(items []*LoadoutItem, prices map[string]*GrenadePrice) *Loadout {
+	loadout := &Loadout{
+		ByType:     make(map[string]*LoadoutTypeCost),
+		Violations: []string{},
+		Suggested:  []*PlayerLoadout{},
+	}
+
+	players := make(map[int]*PlayerLoadout)
+
+	for _, item := range items {
+		t := item.Grenade.Type
+
+		price, ok := prices[t]
+		if !ok {
+			price = &GrenadePrice{Type: t, MaxPerPlayer: MaxGrenadesPerPlayer}
+			loadout.Violations = append(loadout.Violations, fmt.Sprintf("no price configured for type %q", t))
+		}
+
+		if _, ok := loadout.ByType[t]; !ok {
+			loadout.ByType[t] = &LoadoutTypeCost{Price: price.Price}
+		}
+		loadout.ByType[t].Count++
+		loadout.ByType[t].Cost += price.Price
+		loadout.TotalCost += price.Price
+
+		if item.PlayerSlot > 0 {
+			player, ok := players[item.PlayerSlot]
+			if !ok {
+				player = newPlayerLoadout(item.PlayerSlot)
+				players[item.PlayerSlot] = player
+			}
+			player.add(item.Grenade, t, price.Price)
+		}
+	}
+
+	for _, player := range players {
+		loadout.Players = append(loadout.Players, player)
+		loadout.Violations = append(loadout.Violations, player.violations(prices)...)
+	}
+
+	sort.Slice(loadout.Players, func(i, j int) bool {
+		return loadout.Players[i].PlayerSlot < loadout.Players[j].PlayerSlot
+	})
+
+	loadout.Suggested, loadout.Unassigned = suggestSplit(items, prices)
+	if len(loadout.Unassigned) > 0 {
+		loadout.Violations = append(loadout.Violations, fmt.Sprintf("%d grenades do not fit into %d players' carrying limits", len(loadout.Unassigned), TeamSize))
+	}
+
+	return loadout
+}
+
+func newPlayerLoadout(slot int) *PlayerLoadout {
+	return &PlayerLoadout{
+		PlayerSlot: slot,
+		GrenadeIDs: []int64{},
+		Types:      make(map[string]int),
+	}
+}
+
+func (p *PlayerLoadout) add(grenade *Grenade, t string, price int) {
+	p.GrenadeIDs = append(p.GrenadeIDs, grenade.ID)
+	p.Types[t]++
+	p.Cost += price
+}
+
+func (p *PlayerLoadout) canCarry(t string, prices map[string]*GrenadePrice) bool {
+	if len(p.GrenadeIDs) >= MaxGrenadesPerPlayer {
+		return false
+	}
+	if price, ok := prices[t]; ok && p.Types[t] >= price.MaxPerPlayer {
+		return false
+	}
+	return true
+}
+
+func (p *PlayerLoadout) violations(prices map[string]*GrenadePrice) []string {
+	var violations []string
+
+	if len(p.GrenadeIDs) > MaxGrenadesPerPlayer {
+		violations = append(violations, fmt.Sprintf("player %d carries %d grenades, max %d", p.PlayerSlot, len(p.GrenadeIDs), MaxGrenadesPerPlayer))
+	}
+
+	for t, count := range p.Types {
+		if price, ok := prices[t]; ok && count > price.MaxPerPlayer {
+			violations = append(violations, fmt.Sprintf("player %d carries %d %s, max %d", p.PlayerSlot, count, t, price.MaxPerPlayer))
+		}
+	}
+
+	sort.Strings(violations)
+
+	return violations
+}
+
+// suggestSplit жадно раздает гранаты игрокам: сначала самые ограниченные по лимиту типы,
+// каждую гранату получает игрок с наименьшим кол-вом гранат, который еще может ее нести
+func suggestSplit(items []*LoadoutItem, prices map[string]*GrenadePrice) ([]*PlayerLoadout, []int64) {
+	players := make([]*PlayerLoadout, TeamSize)
+	for i := range players {
+		players[i] = newPlayerLoadout(i + 1)
+	}
+
+	sorted := make([]*LoadoutItem, len(items))
+	copy(sorted, items)
+
+	limit := func(t string) int {
+		if price, ok := prices[t]; ok {
+			return price.MaxPerPlayer
+		}
+		return MaxGrenadesPerPlayer
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return limit(sorted[i].Grenade.Type) < limit(sorted[j].Grenade.Type)
+	})
+
+	unassigned := []int64{}
+
+	for _, item := range sorted {
+		t := item.Grenade.Type
+
+		var best *PlayerLoadout
+		for _, player := range players {
+			if !player.canCarry(t, prices) {
+				continue
+			}
+			if best == nil || len(player.GrenadeIDs) < len(best.GrenadeIDs) || (len(player.GrenadeIDs) == len(best.GrenadeIDs) && player.Cost < best.Cost) {
+				best = player
+			}
+		}
+
+		if best == nil {
+			unassigned = append(unassigned, item.Grenade.ID)
+			continue
+		}
+
+		price := 0
+		if p, ok := prices[t]; ok {
+			price = p.Price
+		}
+		best.add(item.Grenade, t, price)
+	}
+
+	return players, unassigned
+}
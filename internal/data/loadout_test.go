@@ -0,0 +1,160 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testPrices = map[string]*GrenadePrice{
+	"smoke":   {Type: "smoke", Price: 300, MaxPerPlayer: 1},
+	"flash":   {Type: "flash", Price: 200, MaxPerPlayer: 2},
+	"he":      {Type: "he", Price: 300, MaxPerPlayer: 1},
+	"molotov": {Type: "molotov", Price: 400, MaxPerPlayer: 1},
+}
+
+func testLoadoutItems(slot int, types ...string) []*LoadoutItem {
+	items := make([]*LoadoutItem, len(types))
+	for i, t := range types {
+		items[i] = &LoadoutItem{
+			Grenade:    &Grenade{ID: int64(slot*100 + i + 1), Type: t},
+			PlayerSlot: slot,
+		}
+	}
+	return items
+}
+
+func TestPlanLoadoutCost(t *testing.T) {
+	items := testLoadoutItems(0, "smoke", "smoke", "flash", "molotov")
+
+	loadout := PlanLoadout(items, testPrices)
+
+	if loadout.TotalCost != 1200 {
+		t.Errorf("total cost = %d, want 1200", loadout.TotalCost)
+	}
+
+	wantByType := map[string]*LoadoutTypeCost{
+		"smoke":   {Count: 2, Price: 300, Cost: 600},
+		"flash":   {Count: 1, Price: 200, Cost: 200},
+		"molotov": {Count: 1, Price: 400, Cost: 400},
+	}
+	if !reflect.DeepEqual(loadout.ByType, wantByType) {
+		t.Errorf("by type = %+v, want %+v", loadout.ByType, wantByType)
+	}
+
+	if len(loadout.Players) != 0 {
+		t.Errorf("players = %+v, want none without player slots", loadout.Players)
+	}
+
+	if len(loadout.Violations) != 0 {
+		t.Errorf("violations = %v, want none", loadout.Violations)
+	}
+}
+
+func TestPlanLoadoutPlayerViolations(t *testing.T) {
+	items := append(testLoadoutItems(2, "smoke", "smoke", "flash", "flash", "he"), testLoadoutItems(1, "flash")...)
+
+	loadout := PlanLoadout(items, testPrices)
+
+	if len(loadout.Players) != 2 || loadout.Players[0].PlayerSlot != 1 || loadout.Players[1].PlayerSlot != 2 {
+		t.Fatalf("players = %+v, want slots 1 and 2", loadout.Players)
+	}
+
+	if loadout.Players[1].Cost != 1300 {
+		t.Errorf("player 2 cost = %d, want 1300", loadout.Players[1].Cost)
+	}
+
+	want := []string{
+		"player 2 carries 2 smoke, max 1",
+		"player 2 carries 5 grenades, max 4",
+	}
+	if !reflect.DeepEqual(loadout.Violations, want) {
+		t.Errorf("violations = %v, want %v", loadout.Violations, want)
+	}
+}
+
+func TestPlanLoadoutUnknownPrice(t *testing.T) {
+	loadout := PlanLoadout(testLoadoutItems(0, "decoy"), testPrices)
+
+	if loadout.TotalCost != 0 {
+		t.Errorf("total cost = %d, want 0", loadout.TotalCost)
+	}
+
+	want := []string{`no price configured for type "decoy"`}
+	if !reflect.DeepEqual(loadout.Violations, want) {
+		t.Errorf("violations = %v, want %v", loadout.Violations, want)
+	}
+}
+
+func TestPlanLoadoutTooManyForTeam(t *testing.T) {
+	items := testLoadoutItems(0, "smoke", "smoke", "smoke", "smoke", "smoke", "smoke")
+
+	loadout := PlanLoadout(items, testPrices)
+
+	if !reflect.DeepEqual(loadout.Unassigned, []int64{6}) {
+		t.Errorf("unassigned = %v, want [6]", loadout.Unassigned)
+	}
+
+	want := []string{"1 grenades do not fit into 5 players' carrying limits"}
+	if !reflect.DeepEqual(loadout.Violations, want) {
+		t.Errorf("violations = %v, want %v", loadout.Violations, want)
+	}
+}
+
+func TestSuggestSplit(t *testing.T) {
+	tests := []struct {
+		name       string
+		types      []string
+		want       map[int][]int64
+		unassigned []int64
+	}{
+		{
+			name:       "one per player",
+			types:      []string{"smoke", "smoke", "smoke"},
+			want:       map[int][]int64{1: {1}, 2: {2}, 3: {3}, 4: {}, 5: {}},
+			unassigned: []int64{},
+		},
+		{
+			name:       "restricted types go first",
+			types:      []string{"flash", "flash", "smoke", "smoke", "smoke", "smoke", "smoke"},
+			want:       map[int][]int64{1: {3, 1}, 2: {4, 2}, 3: {5}, 4: {6}, 5: {7}},
+			unassigned: []int64{},
+		},
+		{
+			name:       "per player type limit",
+			types:      []string{"he", "he", "he", "he", "he", "he", "he"},
+			want:       map[int][]int64{1: {1}, 2: {2}, 3: {3}, 4: {4}, 5: {5}},
+			unassigned: []int64{6, 7},
+		},
+		{
+			name:       "carrying limit",
+			types:      []string{"flash", "flash", "flash", "flash", "flash", "flash", "flash", "flash", "flash", "flash", "flash"},
+			want:       map[int][]int64{1: {1, 6}, 2: {2, 7}, 3: {3, 8}, 4: {4, 9}, 5: {5, 10}},
+			unassigned: []int64{11},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := testLoadoutItems(0, tt.types...)
+
+			players, unassigned := suggestSplit(items, testPrices)
+
+			if len(players) != TeamSize {
+				t.Fatalf("players = %d, want %d", len(players), TeamSize)
+			}
+
+			for _, player := range players {
+				if !reflect.DeepEqual(player.GrenadeIDs, tt.want[player.PlayerSlot]) {
+					t.Errorf("player %d grenades = %v, want %v", player.PlayerSlot, player.GrenadeIDs, tt.want[player.PlayerSlot])
+				}
+				if len(player.GrenadeIDs) > MaxGrenadesPerPlayer {
+					t.Errorf("player %d carries %d grenades", player.PlayerSlot, len(player.GrenadeIDs))
+				}
+			}
+
+			if !reflect.DeepEqual(unassigned, tt.unassigned) {
+				t.Errorf("unassigned = %v, want %v", unassigned, tt.unassigned)
+			}
+		})
+	}
+}
@@ -14,11 +14,47 @@ var (
 type Models struct {
 	Grenades GrenadeModel
 	Images ImageModel
+	Search SearchModel
+	Tags TagModel
+	Executes ExecuteModel
+	GrenadeTimings GrenadeTimingModel
+	GrenadePrices GrenadePriceModel
+	Targets TargetModel
+	Relations RelationModel
+	Reports ReportModel
+	Users UserModel
+	Tokens TokenModel
+	Permissions PermissionModel
+	APIKeys APIKeyModel
+	UserIdentities UserIdentityModel
+	OAuthStates OAuthStateModel
+	RecoveryCodes RecoveryCodeModel
+	GrenadeRevisions GrenadeRevisionModel
+	AuditLog AuditLogModel
+	Callouts CalloutModel
 }
 
 func NewModels(db *sql.DB) Models {
 	return Models{
 		Grenades: GrenadeModel{DB: db},
 		Images: ImageModel{DB: db},
+		Search: SearchModel{DB: db},
+		Tags: TagModel{DB: db},
+		Executes: ExecuteModel{DB: db},
+		GrenadeTimings: GrenadeTimingModel{DB: db},
+		GrenadePrices: GrenadePriceModel{DB: db},
+		Targets: TargetModel{DB: db},
+		Relations: RelationModel{DB: db},
+		Reports: ReportModel{DB: db},
+		Users: UserModel{DB: db},
+		Tokens: TokenModel{DB: db},
+		Permissions: PermissionModel{DB: db},
+		APIKeys: APIKeyModel{DB: db},
+		UserIdentities: UserIdentityModel{DB: db},
+		OAuthStates: OAuthStateModel{DB: db},
+		RecoveryCodes: RecoveryCodeModel{DB: db},
+		GrenadeRevisions: GrenadeRevisionModel{DB: db},
+		AuditLog: AuditLogModel{DB: db},
+		Callouts: CalloutModel{DB: db},
 	}
 }
\ No newline at end of file
@@ -0,0 +1,77 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+const (
+	PermissionGrenadesWrite    = "grenades:write"
+	PermissionGrenadesSubmit   = "grenades:submit"
+	PermissionImagesWrite      = "images:write"
+	PermissionModerationManage = "moderation:manage"
+	PermissionUsersManage      = "users:manage"
+)
+
+// Roles - роли пользователей, набор прав роли хранится в таблице roles_permissions
+var Roles = []string{"player", "editor", "moderator", "admin"}
+
+type Permissions []string
+
+func (p Permissions) Include(code string) bool {
+	for i := range p {
+		if code == p[i] {
+			return true
+		}
+	}
+	return false
+}
+
+func ValidateRole(v *validator.Validator, role string) {
+	v.Check(v.In(role, Roles), "role", "value of role must be player|editor|moderator|admin")
+}
+
+type PermissionModel struct {
+	DB *sql.DB
+}
+
+// GetAllForUser возвращает права пользователя по его роли
+func (m PermissionModel) GetAllForUser(userID int64) (Permissions, error) {
+	query := `
+	SELECT roles_permissions.permission
+	FROM roles_permissions
+	INNER JOIN users ON users.role = roles_permissions.role
+	WHERE users.id = $1
+	ORDER BY roles_permissions.permission`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions Permissions
+
+	for rows.Next() {
+		var permission string
+
+		err := rows.Scan(&permission)
+		if err != nil {
+			return nil, err
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
@@ -0,0 +1,351 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Язык запросов для параметра filter, например:
+//
+//	map:mirage side:T (type:smoke OR type:flash) technique:jumpthrow -tag:oneway
+//
+// Условия через пробел объединяются через AND, поддерживаются OR, NOT/"-" и скобки.
+// Слово без поля ищется полнотекстовым поиском. Запрос разбирается в AST,
+// который затем компилируется в параметризованный SQL - значения передаются
+// только через плейсхолдеры, а поля проверяются по белому списку.
+
+const (
+	filterQueryMaxLength = 500
+	filterQueryMaxTerms  = 30
+	filterQueryMaxDepth  = 10
+)
+
+var ErrInvalidFilterQuery = errors.New("invalid filter query")
+
+// FilterQuery - разобранный запрос
+type FilterQuery struct {
+	root filterNode
+}
+
+type filterNode interface {
+	compile(fields map[string]filterField, args *queryArgs) (string, error)
+}
+
+// filterField возвращает SQL условие для значения поля, значение добавляется в args
+type filterField func(value string, args *queryArgs) string
+
+type andNode struct {
+	children []filterNode
+}
+
+type orNode struct {
+	children []filterNode
+}
+
+type notNode struct {
+	child filterNode
+}
+
+type termNode struct {
+	field string
+	value string
+}
+
+func (n andNode) compile(fields map[string]filterField, args *queryArgs) (string, error) {
+	return compileChildren(n.children, " AND ", fields, args)
+}
+
+func (n orNode) compile(fields map[string]filterField, args *queryArgs) (string, error) {
+	return compileChildren(n.children, " OR ", fields, args)
+}
+
+func (n notNode) compile(fields map[string]filterField, args *queryArgs) (string, error) {
+	child, err := n.child.compile(fields, args)
+	if err != nil {
+		return "", err
+	}
+	return "NOT " + child, nil
+}
+
+// compile без SQL для поля возвращает ошибку, поле не попадает в запрос
+func (n termNode) compile(fields map[string]filterField, args *queryArgs) (string, error) {
+	field, ok := fields[n.field]
+	if !ok {
+		return "", fmt.Errorf("%w: field %q is not supported", ErrInvalidFilterQuery, n.field)
+	}
+	return "(" + field(n.value, args) + ")", nil
+}
+
+func compileChildren(children []filterNode, sep string, fields map[string]filterField, args *queryArgs) (string, error) {
+	parts := make([]string, len(children))
+	for i, child := range children {
+		part, err := child.compile(fields, args)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = part
+	}
+	return "(" + strings.Join(parts, sep) + ")", nil
+}
+
+// ParseFilterQuery разбирает запрос, поля должны быть из safeList,
+// textField - поле, в которое попадают слова без указания поля
+func ParseFilterQuery(s string, safeList []string, textField string) (*FilterQuery, error) {
+	if len(s) > filterQueryMaxLength {
+		return nil, fmt.Errorf("%w: must not be grater than %d bytes", ErrInvalidFilterQuery, filterQueryMaxLength)
+	}
+
+	tokens, err := tokenizeFilterQuery(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens, safeList: safeList, textField: textField}
+
+	root, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidFilterQuery, p.tokens[p.pos].text)
+	}
+
+	return &FilterQuery{root: root}, nil
+}
+
+func (q *FilterQuery) compile(fields map[string]filterField, args *queryArgs) (string, error) {
+	return q.root.compile(fields, args)
+}
+
+type filterTokenKind int
+
+const (
+	tokenTerm filterTokenKind = iota
+	tokenLParen
+	tokenRParen
+	tokenAnd
+	tokenOr
+	tokenNot
+)
+
+type filterToken struct {
+	kind  filterTokenKind
+	text  string
+	field string
+	value string
+}
+
+func tokenizeFilterQuery(s string) ([]filterToken, error) {
+	var tokens []filterToken
+
+	runes := []rune(s)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, filterToken{kind: tokenLParen, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{kind: tokenRParen, text: ")"})
+			i++
+		case r == '-':
+			tokens = append(tokens, filterToken{kind: tokenNot, text: "-"})
+			i++
+		default:
+			// поле или слово до ':' / пробела / скобки
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' && runes[i] != ':' && runes[i] != '"' {
+				i++
+			}
+			word := string(runes[start:i])
+
+			field := ""
+			if i < len(runes) && runes[i] == ':' {
+				field = strings.ToLower(word)
+				i++
+				start = i
+				for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' && runes[i] != '"' {
+					i++
+				}
+				word = string(runes[start:i])
+			}
+
+			// значение в кавычках
+			if word == "" && i < len(runes) && runes[i] == '"' {
+				end := i + 1
+				for end < len(runes) && runes[end] != '"' {
+					end++
+				}
+				if end == len(runes) {
+					return nil, fmt.Errorf("%w: unterminated quoted string", ErrInvalidFilterQuery)
+				}
+				word = string(runes[i+1 : end])
+				i = end + 1
+			}
+
+			if word == "" {
+				return nil, fmt.Errorf("%w: empty value at position %d", ErrInvalidFilterQuery, start)
+			}
+
+			if field == "" {
+				switch word {
+				case "AND":
+					tokens = append(tokens, filterToken{kind: tokenAnd, text: word})
+					continue
+				case "OR":
+					tokens = append(tokens, filterToken{kind: tokenOr, text: word})
+					continue
+				case "NOT":
+					tokens = append(tokens, filterToken{kind: tokenNot, text: word})
+					continue
+				}
+			}
+
+			tokens = append(tokens, filterToken{kind: tokenTerm, text: word, field: field, value: word})
+		}
+	}
+
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens    []filterToken
+	pos       int
+	terms     int
+	safeList  []string
+	textField string
+}
+
+func (p *filterParser) peek() (filterToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return filterToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// or := and ("OR" and)*
+func (p *filterParser) parseOr(depth int) (filterNode, error) {
+	node, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	children := []filterNode{node}
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind != tokenOr {
+			break
+		}
+		p.pos++
+
+		node, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, node)
+	}
+
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return orNode{children: children}, nil
+}
+
+// and := unary (["AND"] unary)*
+func (p *filterParser) parseAnd(depth int) (filterNode, error) {
+	node, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	children := []filterNode{node}
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind == tokenOr || t.kind == tokenRParen {
+			break
+		}
+		if t.kind == tokenAnd {
+			p.pos++
+		}
+
+		node, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, node)
+	}
+
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return andNode{children: children}, nil
+}
+
+// unary := ("NOT" | "-") unary | "(" or ")" | term
+func (p *filterParser) parseUnary(depth int) (filterNode, error) {
+	if depth > filterQueryMaxDepth {
+		return nil, fmt.Errorf("%w: nesting is too deep", ErrInvalidFilterQuery)
+	}
+
+	t, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("%w: unexpected end of query", ErrInvalidFilterQuery)
+	}
+	p.pos++
+
+	switch t.kind {
+	case tokenNot:
+		child, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return notNode{child: child}, nil
+
+	case tokenLParen:
+		node, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := p.peek(); !ok || t.kind != tokenRParen {
+			return nil, fmt.Errorf("%w: missing closing parenthesis", ErrInvalidFilterQuery)
+		}
+		p.pos++
+		return node, nil
+
+	case tokenTerm:
+		p.terms++
+		if p.terms > filterQueryMaxTerms {
+			return nil, fmt.Errorf("%w: must contain a maximum of %d conditions", ErrInvalidFilterQuery, filterQueryMaxTerms)
+		}
+
+		field := t.field
+		if field == "" {
+			field = p.textField
+		}
+
+		if !containsString(p.safeList, field) {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidFilterQuery, field)
+		}
+
+		return termNode{field: field, value: t.value}, nil
+
+	default:
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidFilterQuery, t.text)
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,236 @@
+package data
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+var testFilterFields = map[string]filterField{
+	"map":   equalFilterField("map"),
+	"side":  equalFilterField("side"),
+	"type":  equalFilterField("type"),
+	"title": equalFilterField("title"),
+}
+
+var testFilterSafeList = []string{"map", "side", "type", "title"}
+
+func TestParseFilterQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		sql   string
+		args  queryArgs
+	}{
+		{
+			name:  "single term",
+			query: "map:mirage",
+			sql:   "(map = $1)",
+			args:  queryArgs{"mirage"},
+		},
+		{
+			name:  "implicit and",
+			query: "map:mirage side:T",
+			sql:   "((map = $1) AND (side = $2))",
+			args:  queryArgs{"mirage", "T"},
+		},
+		{
+			name:  "explicit and",
+			query: "map:mirage AND side:T",
+			sql:   "((map = $1) AND (side = $2))",
+			args:  queryArgs{"mirage", "T"},
+		},
+		{
+			name:  "and binds tighter than or",
+			query: "map:mirage side:T OR type:smoke",
+			sql:   "(((map = $1) AND (side = $2)) OR (type = $3))",
+			args:  queryArgs{"mirage", "T", "smoke"},
+		},
+		{
+			name:  "parentheses override precedence",
+			query: "map:mirage (type:smoke OR type:flash)",
+			sql:   "((map = $1) AND ((type = $2) OR (type = $3)))",
+			args:  queryArgs{"mirage", "smoke", "flash"},
+		},
+		{
+			name:  "dash negation",
+			query: "-side:CT",
+			sql:   "NOT (side = $1)",
+			args:  queryArgs{"CT"},
+		},
+		{
+			name:  "NOT negation of group",
+			query: "NOT (type:smoke OR type:flash)",
+			sql:   "NOT ((type = $1) OR (type = $2))",
+			args:  queryArgs{"smoke", "flash"},
+		},
+		{
+			name:  "double negation",
+			query: "NOT -map:dust2",
+			sql:   "NOT NOT (map = $1)",
+			args:  queryArgs{"dust2"},
+		},
+		{
+			name:  "quoted value with spaces",
+			query: `title:"one way"`,
+			sql:   "(title = $1)",
+			args:  queryArgs{"one way"},
+		},
+		{
+			name:  "quoted value keeps operators and parentheses",
+			query: `title:"A OR (B)"`,
+			sql:   "(title = $1)",
+			args:  queryArgs{"A OR (B)"},
+		},
+		{
+			name:  "bare word goes to text field",
+			query: "jungle",
+			sql:   "(title = $1)",
+			args:  queryArgs{"jungle"},
+		},
+		{
+			name:  "field name is case insensitive",
+			query: "MAP:mirage",
+			sql:   "(map = $1)",
+			args:  queryArgs{"mirage"},
+		},
+		{
+			name:  "lowercase or is a term",
+			query: "map:mirage or",
+			sql:   "((map = $1) AND (title = $2))",
+			args:  queryArgs{"mirage", "or"},
+		},
+		{
+			name:  "injection stays in args",
+			query: `title:"'; DROP TABLE grenades; --"`,
+			sql:   "(title = $1)",
+			args:  queryArgs{"'; DROP TABLE grenades; --"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseFilterQuery(tt.query, testFilterSafeList, "title")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			args := queryArgs{}
+			sql, err := q.compile(testFilterFields, &args)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if sql != tt.sql {
+				t.Errorf("sql = %q, want %q", sql, tt.sql)
+			}
+
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %v, want %v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestParseFilterQueryArgsContinueNumbering(t *testing.T) {
+	q, err := ParseFilterQuery("map:mirage", testFilterSafeList, "title")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	args := queryArgs{"existing"}
+	sql, err := q.compile(testFilterFields, &args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if sql != "(map = $2)" {
+		t.Errorf("sql = %q, want %q", sql, "(map = $2)")
+	}
+}
+
+func TestParseFilterQueryErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		message string
+	}{
+		{"empty query", "", "unexpected end of query"},
+		{"unknown field", "author:me", `unknown field "author"`},
+		{"unterminated quote", `title:"one way`, "unterminated quoted string"},
+		{"empty value", "map: side:T", "empty value"},
+		{"missing closing parenthesis", "(map:mirage", "missing closing parenthesis"},
+		{"unexpected closing parenthesis", "map:mirage)", `unexpected ")"`},
+		{"dangling or", "map:mirage OR", "unexpected end of query"},
+		{"leading and", "AND map:mirage", `unexpected "AND"`},
+		{"dangling not", "map:mirage -", "unexpected end of query"},
+		{"empty group", "()", `unexpected ")"`},
+		{"too long", strings.Repeat("a", filterQueryMaxLength+1), "must not be grater than 500 bytes"},
+		{"too many terms", strings.Repeat("map:mirage ", filterQueryMaxTerms+1), "maximum of 30 conditions"},
+		{"too deep parentheses", strings.Repeat("(", filterQueryMaxDepth+1) + "map:mirage" + strings.Repeat(")", filterQueryMaxDepth+1), "nesting is too deep"},
+		{"too deep negation", strings.Repeat("-", filterQueryMaxDepth+1) + "map:mirage", "nesting is too deep"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFilterQuery(tt.query, testFilterSafeList, "title")
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if !errors.Is(err, ErrInvalidFilterQuery) {
+				t.Errorf("error %v is not ErrInvalidFilterQuery", err)
+			}
+
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("error = %q, want it to contain %q", err.Error(), tt.message)
+			}
+		})
+	}
+}
+
+func TestParseFilterQueryLimitsBoundary(t *testing.T) {
+	terms := strings.TrimSpace(strings.Repeat("map:mirage ", filterQueryMaxTerms))
+	if _, err := ParseFilterQuery(terms, testFilterSafeList, "title"); err != nil {
+		t.Errorf("%d terms: unexpected error: %v", filterQueryMaxTerms, err)
+	}
+
+	nested := strings.Repeat("(", filterQueryMaxDepth) + "map:mirage" + strings.Repeat(")", filterQueryMaxDepth)
+	if _, err := ParseFilterQuery(nested, testFilterSafeList, "title"); err != nil {
+		t.Errorf("depth %d: unexpected error: %v", filterQueryMaxDepth, err)
+	}
+}
+
+func TestFilterQueryFieldWithoutSQL(t *testing.T) {
+	q, err := ParseFilterQuery("type:smoke OR -map:mirage", testFilterSafeList, "title")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	args := queryArgs{}
+	_, err = q.compile(map[string]filterField{"type": equalFilterField("type")}, &args)
+	if !errors.Is(err, ErrInvalidFilterQuery) {
+		t.Errorf("error = %v, want ErrInvalidFilterQuery for a field without SQL mapping", err)
+	}
+}
+
+// technique из примера запроса ищется по тегам
+func TestGrenadeFilterFieldsTechnique(t *testing.T) {
+	q, err := ParseFilterQuery("technique:jumpthrow", []string{"technique"}, "technique")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	args := queryArgs{}
+	sql, err := q.compile(grenadeFilterFields, &args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(sql, "grenades_tags") || !reflect.DeepEqual(args, queryArgs{pq.Array([]string{"jumpthrow"})}) {
+		t.Errorf("sql = %q, args = %v, want a tag condition for jumpthrow", sql, args)
+	}
+}
@@ -0,0 +1,55 @@
+package data
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+var recoveryCodeRX = regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+
+func TestGenerateRecoveryCode(t *testing.T) {
+	seen := make(map[string]bool)
+
+	for i := 0; i < 100; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !recoveryCodeRX.MatchString(code) {
+			t.Errorf("code %q does not match xxxxx-xxxxx", code)
+		}
+
+		if seen[code] {
+			t.Errorf("code %q generated twice", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	hash := hashRecoveryCode("abcde-fghij")
+
+	if len(hash) != 32 {
+		t.Fatalf("hash length = %d, want 32", len(hash))
+	}
+
+	// пользователь может ввести код без дефиса, с пробелами или заглавными буквами
+	for _, input := range []string{"abcdefghij", "ABCDE-FGHIJ", " abcde fghij ", "Abcde - Fghij"} {
+		if !bytes.Equal(hashRecoveryCode(input), hash) {
+			t.Errorf("hash of %q differs from hash of abcde-fghij", input)
+		}
+	}
+
+	for _, input := range []string{"abcde-fghik", "abcde-fghi", ""} {
+		if bytes.Equal(hashRecoveryCode(input), hash) {
+			t.Errorf("hash of %q must differ from hash of abcde-fghij", input)
+		}
+	}
+
+	// в БД хранится хэш, а не сам код
+	if bytes.Contains(hash, []byte("abcdefghij")) {
+		t.Error("hash contains the plaintext code")
+	}
+}
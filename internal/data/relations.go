@@ -0,0 +1,271 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+var ErrDuplicateRelation = errors.New("duplicate relation")
+
+// RelationKinds - типы связей между раскидками:
+// counters - from разбивает/выжигает to, follows - from кидается после to,
+// pairs_with - кидаются вместе, mirror - аналог на другой стороне
+var RelationKinds = []string{"counters", "follows", "pairs_with", "mirror"}
+
+// symmetricRelationKinds - связи без направления
+var symmetricRelationKinds = []string{"pairs_with", "mirror"}
+
+type GrenadeRelation struct {
+	ID            int64     `json:"id"`
+	FromGrenadeID int64     `json:"from_grenade_id"`
+	ToGrenadeID   int64     `json:"to_grenade_id"`
+	Kind          string    `json:"kind"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// RelatedGrenade - связанная раскидка с точки зрения одной гранаты,
+// Direction - outgoing если граната в from, incoming если в to
+type RelatedGrenade struct {
+	RelationID int64    `json:"relation_id"`
+	Kind       string   `json:"kind"`
+	Direction  string   `json:"direction"`
+	Grenade    *Grenade `json:"grenade"`
+}
+
+type RelationGraph struct {
+	Map   string             `json:"map"`
+	Nodes []*Grenade         `json:"nodes"`
+	Edges []*GrenadeRelation `json:"edges"`
+}
+
+type RelationModel struct {
+	DB *sql.DB
+}
+
+// ValidateRelation проверяет связь между from и to
+func ValidateRelation(relation *GrenadeRelation, from, to *Grenade, v *validator.Validator) {
+	v.Check(v.In(relation.Kind, RelationKinds), "kind", "value of kind must be counters|follows|pairs_with|mirror")
+	v.Check(from.ID != to.ID, "to_grenade_id", "must not be the same grenade")
+	v.Check(from.Map == to.Map, "to_grenade_id", "grenade map must match")
+
+	if relation.Kind == "mirror" {
+		v.Check(from.Side != to.Side, "to_grenade_id", "mirror grenade must be for the other side")
+	}
+}
+
+func (m RelationModel) Get(id int64) (*GrenadeRelation, error) {
+	query := `
+	SELECT id, from_grenade_id, to_grenade_id, kind, created_at
+	FROM grenade_relations
+	WHERE id = $1`
+
+	var relation GrenadeRelation
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&relation.ID,
+		&relation.FromGrenadeID,
+		&relation.ToGrenadeID,
+		&relation.Kind,
+		&relation.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &relation, nil
+}
+
+// Insert сохраняет связь, для симметричных связей обратная связь считается дублем
+func (m RelationModel) Insert(relation *GrenadeRelation) error {
+	query := `
+	INSERT INTO grenade_relations (from_grenade_id, to_grenade_id, kind)
+	SELECT $1, $2, $3
+	WHERE NOT ($3 = ANY($4) AND EXISTS (
+		SELECT 1 FROM grenade_relations
+		WHERE from_grenade_id = $2 AND to_grenade_id = $1 AND kind = $3))
+	RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{relation.FromGrenadeID, relation.ToGrenadeID, relation.Kind, pq.Array(symmetricRelationKinds)}
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&relation.ID, &relation.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows), isUniqueViolation(err):
+			return ErrDuplicateRelation
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m RelationModel) Delete(id int64) error {
+	query := `
+	DELETE FROM grenade_relations
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetByGrenadeID возвращает связи гранаты в обе стороны вместе со связанными опубликованными гранатами
+func (m RelationModel) GetByGrenadeID(grenadeID int64) ([]*RelatedGrenade, error) {
+	query := fmt.Sprintf(`
+	SELECT r.id, r.kind, r.direction, %s, 0
+	FROM (
+		SELECT id, kind, 'outgoing' AS direction, to_grenade_id AS grenade_id
+		FROM grenade_relations WHERE from_grenade_id = $1
+		UNION ALL
+		SELECT id, kind, 'incoming', from_grenade_id
+		FROM grenade_relations WHERE to_grenade_id = $1
+	) r
+	INNER JOIN grenades ON grenades.id = r.grenade_id
+	WHERE grenades.status = 'published' AND grenades.deleted_at IS NULL
+	ORDER BY r.kind, r.id`, grenadeColumns)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, grenadeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	related := []*RelatedGrenade{}
+
+	for rows.Next() {
+		var item RelatedGrenade
+		var grenade Grenade
+
+		err := rows.Scan(append([]interface{}{&item.RelationID, &item.Kind, &item.Direction}, grenade.listFields()...)...)
+		if err != nil {
+			return nil, err
+		}
+
+		item.Grenade = &grenade
+		related = append(related, &item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return related, nil
+}
+
+// GetGraph возвращает граф связей карты: опубликованные раскидки, у которых есть связи, и сами связи
+func (m RelationModel) GetGraph(csMap string) (*RelationGraph, error) {
+	graph := &RelationGraph{
+		Map:   csMap,
+		Nodes: []*Grenade{},
+		Edges: []*GrenadeRelation{},
+	}
+
+	edgesQuery := `
+	SELECT r.id, r.from_grenade_id, r.to_grenade_id, r.kind, r.created_at
+	FROM grenade_relations r
+	INNER JOIN grenades g ON g.id = r.from_grenade_id
+	INNER JOIN grenades g_to ON g_to.id = r.to_grenade_id
+	WHERE g.map = $1 AND g.status = 'published' AND g_to.status = 'published'
+		AND g.deleted_at IS NULL AND g_to.deleted_at IS NULL
+	ORDER BY r.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, edgesQuery, csMap)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+
+	for rows.Next() {
+		var relation GrenadeRelation
+
+		err := rows.Scan(
+			&relation.ID,
+			&relation.FromGrenadeID,
+			&relation.ToGrenadeID,
+			&relation.Kind,
+			&relation.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		graph.Edges = append(graph.Edges, &relation)
+		ids = append(ids, relation.FromGrenadeID, relation.ToGrenadeID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return graph, nil
+	}
+
+	nodes, err := GrenadeModel{DB: m.DB}.GetByIDs(uniqueIDs(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range uniqueIDs(ids) {
+		if node, ok := nodes[id]; ok {
+			graph.Nodes = append(graph.Nodes, node)
+		}
+	}
+
+	return graph, nil
+}
+
+func uniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]bool)
+	unique := []int64{}
+
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	return unique
+}
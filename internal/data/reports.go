@@ -0,0 +1,212 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+// ReportStatuses - open ждет модератора, resolved - раскидка работает или жалоба отклонена,
+// confirmed - раскидка отмечена сломанной
+var ReportStatuses = []string{"open", "resolved", "confirmed"}
+
+// Report - жалоба игрока на то, что раскидка больше не работает
+type Report struct {
+	ID         int64      `json:"id"`
+	GrenadeID  int64      `json:"grenade_id"`
+	Reason     string     `json:"reason"`
+	Build      string     `json:"build,omitempty"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	Version    int32      `json:"version"`
+	Grenade    *Grenade   `json:"grenade,omitempty"`
+}
+
+type ReportModel struct {
+	DB *sql.DB
+}
+
+func ValidateReport(report *Report, v *validator.Validator) {
+	v.Check(report.Reason != "", "reason", "must be provided")
+	v.Check(len(report.Reason) <= 500, "reason", "must not be grater than 500 bytes")
+
+	v.Check(len(report.Build) <= 50, "build", "must not be grater than 50 bytes")
+}
+
+func (m ReportModel) Get(id int64) (*Report, error) {
+	query := `
+	SELECT id, grenade_id, reason, build, status, created_at, resolved_at, version
+	FROM grenade_reports
+	WHERE id = $1`
+
+	var report Report
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(report.fields()...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &report, nil
+}
+
+func (r *Report) fields() []interface{} {
+	return []interface{}{
+		&r.ID,
+		&r.GrenadeID,
+		&r.Reason,
+		&r.Build,
+		&r.Status,
+		&r.CreatedAt,
+		&r.ResolvedAt,
+		&r.Version,
+	}
+}
+
+func (m ReportModel) Insert(report *Report) error {
+	query := `
+	INSERT INTO grenade_reports (grenade_id, reason, build)
+	VALUES ($1, $2, $3)
+	RETURNING id, status, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{report.GrenadeID, report.Reason, report.Build}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&report.ID, &report.Status, &report.CreatedAt, &report.Version)
+}
+
+// GetAll - очередь жалоб для модераторов, пустой status и grenadeID 0 не фильтруют
+func (m ReportModel) GetAll(status string, grenadeID int64, filters Filters) ([]*Report, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, grenade_id, reason, build, status, created_at, resolved_at, version
+	FROM grenade_reports
+	WHERE (status = $1 OR $1 = '') AND (grenade_id = $2 OR $2 = 0)
+	ORDER BY %s %s, id ASC
+	LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{status, grenadeID, filters.limit(), filters.offset()}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	reports := []*Report{}
+
+	for rows.Next() {
+		var report Report
+
+		err := rows.Scan(append([]interface{}{&totalRecords}, report.fields()...)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		reports = append(reports, &report)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return reports, metadata, nil
+}
+
+// Resolve закрывает открытую жалобу без изменения раскидки
+func (m ReportModel) Resolve(report *Report) error {
+	query := `
+	UPDATE grenade_reports
+	SET status = 'resolved', resolved_at = NOW(), version = version + 1
+	WHERE id = $1 AND version = $2 AND status = 'open'
+	RETURNING status, resolved_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, report.ID, report.Version).Scan(&report.Status, &report.ResolvedAt, &report.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MarkBroken отмечает раскидку из жалобы сломанной и подтверждает все открытые жалобы на нее
+func (m ReportModel) MarkBroken(report *Report, editorID int64) error {
+	reportQuery := `
+	UPDATE grenade_reports
+	SET status = 'confirmed', resolved_at = NOW(), version = version + 1
+	WHERE id = $1 AND version = $2 AND status = 'open'
+	RETURNING status, resolved_at, version`
+
+	othersQuery := `
+	UPDATE grenade_reports
+	SET status = 'confirmed', resolved_at = NOW(), version = version + 1
+	WHERE grenade_id = $1 AND status = 'open'`
+
+	grenadeQuery := `
+	UPDATE grenades
+	SET verification_status = 'broken', version = version + 1
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, reportQuery, report.ID, report.Version).Scan(&report.Status, &report.ResolvedAt, &report.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, othersQuery, report.GrenadeID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, grenadeQuery, report.GrenadeID)
+	if err != nil {
+		return err
+	}
+
+	err = insertGrenadeRevision(ctx, tx, report.GrenadeID, editorID, fmt.Sprintf("verification: broken, report %d", report.ID))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
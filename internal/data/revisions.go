@@ -0,0 +1,210 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+// GrenadeRevision - снимок строки гранаты после изменения, сохраняется на каждую новую версию
+type GrenadeRevision struct {
+	ID        int64           `json:"id"`
+	GrenadeID int64           `json:"grenade_id"`
+	Version   int32           `json:"version"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	EditorID  int64           `json:"editor_id,omitempty"`
+	Note      string          `json:"note,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// FieldChange - значения поля в двух ревизиях
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// execer - *sql.DB или *sql.Tx, ревизия пишется в той же транзакции, что и изменение
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertGrenadeRevision сохраняет текущую строку гранаты вместе с тегами как ревизию её версии
+func insertGrenadeRevision(ctx context.Context, db execer, grenadeID, editorID int64, note string) error {
+	query := `
+	INSERT INTO grenade_revisions (grenade_id, version, data, editor_id, note)
+	SELECT id, version, to_jsonb(grenades) - 'search' || jsonb_build_object('tags', COALESCE((
+		SELECT jsonb_agg(t.name ORDER BY t.name) FROM grenades_tags gt
+		INNER JOIN tags t ON t.id = gt.tag_id
+		WHERE gt.grenade_id = grenades.id), '[]'::jsonb)), NULLIF($2, 0), $3
+	FROM grenades
+	WHERE id = $1`
+
+	_, err := db.ExecContext(ctx, query, grenadeID, editorID, note)
+	return err
+}
+
+func ValidateRevisionNote(v *validator.Validator, note string) {
+	v.Check(len(note) <= 500, "note", "must not be grater than 500 bytes")
+}
+
+// DiffRevisions возвращает поля, которые отличаются в ревизиях from и to
+func DiffRevisions(from, to *GrenadeRevision) (map[string]FieldChange, error) {
+	var fromData, toData map[string]interface{}
+
+	if err := json.Unmarshal(from.Data, &fromData); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(to.Data, &toData); err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]FieldChange)
+
+	for key, value := range toData {
+		if key == "version" {
+			continue
+		}
+		if !reflect.DeepEqual(fromData[key], value) {
+			changes[key] = FieldChange{From: fromData[key], To: value}
+		}
+	}
+
+	// поля, которых нет в более поздней ревизии
+	for key, value := range fromData {
+		if _, ok := toData[key]; !ok {
+			changes[key] = FieldChange{From: value, To: nil}
+		}
+	}
+
+	return changes, nil
+}
+
+// RestoreContent переносит в гранату содержимое ревизии и возвращает её теги. Статусы проверки
+// и публикации не восстанавливаются, они меняются только через свои эндпоинты.
+// В ревизиях, сохраненных до появления тегов в снимке, тегов нет - тогда возвращается nil
+func (r *GrenadeRevision) RestoreContent(grenade *Grenade) ([]string, error) {
+	var content struct {
+		Map         string    `json:"map"`
+		Title       string    `json:"title"`
+		Description string    `json:"description"`
+		Type        string    `json:"type"`
+		Side        string    `json:"side"`
+		TargetID    *int64    `json:"target_id"`
+		Credit      string    `json:"credit"`
+		Tags        *[]string `json:"tags"`
+	}
+
+	if err := json.Unmarshal(r.Data, &content); err != nil {
+		return nil, fmt.Errorf("revision %d: %w", r.Version, err)
+	}
+
+	grenade.Map = content.Map
+	grenade.Title = content.Title
+	grenade.Description = content.Description
+	grenade.Type = content.Type
+	grenade.Side = content.Side
+	grenade.Credit = content.Credit
+
+	grenade.TargetID = 0
+	if content.TargetID != nil {
+		grenade.TargetID = *content.TargetID
+	}
+
+	if content.Tags == nil {
+		return nil, nil
+	}
+
+	return *content.Tags, nil
+}
+
+type GrenadeRevisionModel struct {
+	DB *sql.DB
+}
+
+func (m GrenadeRevisionModel) Get(grenadeID int64, version int32) (*GrenadeRevision, error) {
+	query := `
+	SELECT id, grenade_id, version, data, COALESCE(editor_id, 0), note, created_at
+	FROM grenade_revisions
+	WHERE grenade_id = $1 AND version = $2`
+
+	var revision GrenadeRevision
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, grenadeID, version).Scan(
+		&revision.ID,
+		&revision.GrenadeID,
+		&revision.Version,
+		&revision.Data,
+		&revision.EditorID,
+		&revision.Note,
+		&revision.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &revision, nil
+}
+
+// GetAll возвращает ревизии гранаты без снимков, снимок отдается в Get
+func (m GrenadeRevisionModel) GetAll(grenadeID int64, filters Filters) ([]*GrenadeRevision, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, grenade_id, version, COALESCE(editor_id, 0), note, created_at
+	FROM grenade_revisions
+	WHERE grenade_id = $1
+	ORDER BY %s %s, id ASC
+	LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, grenadeID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	revisions := []*GrenadeRevision{}
+
+	for rows.Next() {
+		var revision GrenadeRevision
+
+		err := rows.Scan(
+			&totalRecords,
+			&revision.ID,
+			&revision.GrenadeID,
+			&revision.Version,
+			&revision.EditorID,
+			&revision.Note,
+			&revision.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		revisions = append(revisions, &revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return revisions, metadata, nil
+}
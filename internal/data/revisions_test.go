@@ -0,0 +1,39 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRevisionRestoreContentTags(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		tags []string
+	}{
+		{"tags", `{"map": "mirage", "target_id": 3, "tags": ["oneway", "jumpthrow"]}`, []string{"oneway", "jumpthrow"}},
+		{"no tags", `{"map": "mirage", "target_id": null, "tags": []}`, []string{}},
+		// ревизия, сохраненная до тегов в снимке, не трогает теги
+		{"snapshot without tags", `{"map": "mirage", "target_id": null}`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revision := &GrenadeRevision{Version: 2, Data: []byte(tt.data)}
+			grenade := &Grenade{Map: "inferno", TargetID: 5}
+
+			tags, err := revision.RestoreContent(grenade)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(tags, tt.tags) {
+				t.Errorf("tags = %#v, want %#v", tags, tt.tags)
+			}
+
+			if grenade.Map != "mirage" {
+				t.Errorf("map = %q, want mirage", grenade.Map)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+// порог word_similarity для подсказок, с дефолтным 0.6 опечатки вроде "jungel" не находятся
+const suggestSimilarityThreshold = "0.3"
+
+type Suggestion struct {
+	Kind  string  `json:"kind"`
+	Value string  `json:"value"`
+	Map   string  `json:"map,omitempty"`
+	Score float64 `json:"score"`
+}
+
+type SearchModel struct {
+	DB *sql.DB
+}
+
+func ValidateSuggestQuery(q string, v *validator.Validator) {
+	v.Check(q != "", "q", "must be provided")
+	v.Check(len(q) <= 100, "q", "must not be grater than 100 bytes")
+}
+
+// Suggest ищет похожие названия гранат, каллауты и карты по триграммам,
+// limit - максимальное кол-во подсказок каждого вида
+func (m SearchModel) Suggest(q string, limit int) ([]*Suggestion, error) {
+	query := `
+	(SELECT 'title', title, '', max(word_similarity($1, title)) AS score
+	FROM grenades
+	WHERE $1 <% title AND status = 'published' AND deleted_at IS NULL
+	GROUP BY title
+	ORDER BY score DESC
+	LIMIT $2)
+	UNION ALL
+	(SELECT 'callout', name, map, word_similarity($1, name) AS score
+	FROM callouts
+	WHERE $1 <% name
+	ORDER BY score DESC
+	LIMIT $2)
+	UNION ALL
+	(SELECT 'map', map, map, max(word_similarity($1, map)) AS score
+	FROM grenades
+	WHERE $1 <% map AND status = 'published' AND deleted_at IS NULL
+	GROUP BY map
+	ORDER BY score DESC
+	LIMIT $2)
+	ORDER BY score DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// порог задаем через SET LOCAL, чтобы оператор <% использовал GIN индексы
+	tx, err := m.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)", suggestSimilarityThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, query, q, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []*Suggestion{}
+
+	for rows.Next() {
+		var suggestion Suggestion
+
+		err := rows.Scan(
+			&suggestion.Kind,
+			&suggestion.Value,
+			&suggestion.Map,
+			&suggestion.Score,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		suggestions = append(suggestions, &suggestion)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
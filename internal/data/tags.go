@@ -0,0 +1,264 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+var (
+	ErrDuplicateTag = errors.New("duplicate tag")
+	ErrUnknownTag   = errors.New("unknown tag")
+)
+
+type Tag struct {
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	Version int32  `json:"version"`
+}
+
+type TagModel struct {
+	DB *sql.DB
+}
+
+func ValidateTag(tag *Tag, v *validator.Validator) {
+	v.Check(tag.Name != "", "name", "must be provided")
+	v.Check(len(tag.Name) <= 50, "name", "must not be grater than 50 bytes")
+}
+
+func ValidateTagNames(names []string, v *validator.Validator) {
+	v.Check(len(names) <= 20, "tags", "must contain a maximum of 20 tags")
+
+	for _, name := range names {
+		v.Check(name != "", "tags", "must not contain empty values")
+		v.Check(len(name) <= 50, "tags", "must not contain values grater than 50 bytes")
+	}
+}
+
+func (m TagModel) Get(id int64) (*Tag, error) {
+	query := `
+	SELECT id, name, version
+	FROM tags
+	WHERE id = $1`
+
+	var tag Tag
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&tag.ID, &tag.Name, &tag.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &tag, nil
+}
+
+func (m TagModel) GetAll() ([]*Tag, error) {
+	query := `
+	SELECT id, name, version
+	FROM tags
+	ORDER BY name`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*Tag{}
+
+	for rows.Next() {
+		var tag Tag
+
+		err := rows.Scan(&tag.ID, &tag.Name, &tag.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, &tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (m TagModel) Insert(tag *Tag) error {
+	query := `
+	INSERT INTO tags (name)
+	VALUES ($1)
+	RETURNING id, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, tag.Name).Scan(&tag.ID, &tag.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicateTag
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m TagModel) Update(tag *Tag) error {
+	query := `
+	UPDATE tags
+	SET name = $1, version = version + 1
+	WHERE id = $2 AND version = $3
+	RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, tag.Name, tag.ID, tag.Version).Scan(&tag.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		case isUniqueViolation(err):
+			return ErrDuplicateTag
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m TagModel) Delete(id int64) error {
+	query := `
+	DELETE FROM tags
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetByGrenadeIDs возвращает названия тегов для каждой гранаты одним запросом
+func (m TagModel) GetByGrenadeIDs(grenadeIDs []int64) (map[int64][]string, error) {
+	query := `
+	SELECT gt.grenade_id, t.name
+	FROM grenades_tags gt
+	INNER JOIN tags t ON t.id = gt.tag_id
+	WHERE gt.grenade_id = ANY($1)
+	ORDER BY t.name`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(grenadeIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make(map[int64][]string)
+
+	for rows.Next() {
+		var (
+			grenadeID int64
+			name      string
+		)
+
+		err := rows.Scan(&grenadeID, &name)
+		if err != nil {
+			return nil, err
+		}
+
+		tags[grenadeID] = append(tags[grenadeID], name)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// replaceGrenadeTags заменяет теги гранаты в транзакции tx, все теги должны существовать
+func replaceGrenadeTags(ctx context.Context, tx *sql.Tx, grenadeID int64, names []string) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM grenades_tags WHERE grenade_id = $1", grenadeID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO grenades_tags (grenade_id, tag_id)
+	SELECT $1, id FROM tags WHERE name = ANY($2)`
+
+	result, err := tx.ExecContext(ctx, query, grenadeID, pq.Array(names))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != int64(len(uniqueStrings(names))) {
+		return ErrUnknownTag
+	}
+
+	return nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool)
+	unique := []string{}
+
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+
+	return unique
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
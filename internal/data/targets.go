@@ -0,0 +1,191 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/w3qxst1ck/cs2-grenades/internal/validator"
+)
+
+// ErrTargetInUse - на цель ссылаются раскидки, удалить ее нельзя
+var ErrTargetInUse = errors.New("target in use")
+
+// Target - точка, в которую можно попасть несколькими раскидками (вариантами)
+type Target struct {
+	ID          int64      `json:"id"`
+	Map         string     `json:"map"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Version     int32      `json:"version"`
+	Variants    []*Grenade `json:"variants,omitempty"`
+}
+
+type TargetModel struct {
+	DB *sql.DB
+}
+
+func ValidateTarget(target *Target, v *validator.Validator) {
+	v.Check(target.Map != "", "map", "must be provided")
+	v.Check(len(target.Map) <= 100, "map", "must not be grater than 100 bytes")
+
+	v.Check(target.Title != "", "title", "must be provided")
+	v.Check(len(target.Title) <= 500, "title", "must not be grater than 500 bytes")
+
+	v.Check(len(target.Description) <= 700, "description", "must not be grater than 700 bytes")
+}
+
+// ValidateGrenadeTarget проверяет, что вариант находится на той же карте, что и цель
+func ValidateGrenadeTarget(grenade *Grenade, target *Target, v *validator.Validator) {
+	v.Check(grenade.Map == target.Map, "target_id", "grenade map must match target map")
+}
+
+func (m TargetModel) Get(id int64) (*Target, error) {
+	query := `
+	SELECT id, map, title, description, version
+	FROM targets
+	WHERE id = $1`
+
+	var target Target
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&target.ID,
+		&target.Map,
+		&target.Title,
+		&target.Description,
+		&target.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &target, nil
+}
+
+func (m TargetModel) GetAll(csMap string, filters Filters) ([]*Target, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, map, title, description, version
+	FROM targets
+	WHERE (map = $1 OR $1 = '')
+	ORDER BY %s %s, id ASC
+	LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, csMap, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	targets := []*Target{}
+
+	for rows.Next() {
+		var target Target
+
+		err := rows.Scan(
+			&totalRecords,
+			&target.ID,
+			&target.Map,
+			&target.Title,
+			&target.Description,
+			&target.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		targets = append(targets, &target)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return targets, metadata, nil
+}
+
+func (m TargetModel) Insert(target *Target) error {
+	query := `
+	INSERT INTO targets (map, title, description)
+	VALUES ($1, $2, $3)
+	RETURNING id, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{target.Map, target.Title, target.Description}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&target.ID, &target.Version)
+}
+
+func (m TargetModel) Update(target *Target) error {
+	query := `
+	UPDATE targets
+	SET map = $1, title = $2, description = $3, version = version + 1
+	WHERE id = $4 AND version = $5
+	RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{target.Map, target.Title, target.Description, target.ID, target.Version}
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&target.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete удаляет цель. Цель с вариантами (в том числе удаленными) не удаляется,
+// иначе раскидки молча потеряли бы target_id без новой версии и ревизии
+func (m TargetModel) Delete(id int64) error {
+	query := `
+	DELETE FROM targets
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		switch {
+		case isForeignKeyViolation(err):
+			return ErrTargetInUse
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
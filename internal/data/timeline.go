@@ -0,0 +1,237 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// GrenadeTiming - длительности фаз гранаты одного типа, хранятся в таблице grenade_timings.
+// Отсчет от броска: detonate -> bloom (полный эффект) -> effect -> fade (рассеивание)
+type GrenadeTiming struct {
+	Type       string `json:"type"`
+	DetonateMs int    `json:"detonate_ms"`
+	BloomMs    int    `json:"bloom_ms"`
+	EffectMs   int    `json:"effect_ms"`
+	FadeMs     int    `json:"fade_ms"`
+	// Covers - граната закрывает обзор и учитывается в покрытии
+	Covers bool `json:"covers"`
+}
+
+type GrenadeTimingModel struct {
+	DB *sql.DB
+}
+
+func (m GrenadeTimingModel) GetAll() (map[string]*GrenadeTiming, error) {
+	query := `
+	SELECT type, detonate_ms, bloom_ms, effect_ms, fade_ms, covers
+	FROM grenade_timings`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	timings := make(map[string]*GrenadeTiming)
+
+	for rows.Next() {
+		var timing GrenadeTiming
+
+		err := rows.Scan(
+			&timing.Type,
+			&timing.DetonateMs,
+			&timing.BloomMs,
+			&timing.EffectMs,
+			&timing.FadeMs,
+			&timing.Covers,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		timings[timing.Type] = &timing
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return timings, nil
+}
+
+type TimelineEvent struct {
+	Position     int    `json:"position"`
+	GrenadeID    int64  `json:"grenade_id"`
+	TargetID     int64  `json:"target_id"`
+	Title        string `json:"title"`
+	Type         string `json:"type"`
+	PlayerSlot   int    `json:"player_slot"`
+	ThrowAtMs    int    `json:"throw_at_ms"`
+	DetonateAtMs int    `json:"detonate_at_ms"`
+	FullAtMs     int    `json:"full_at_ms"`
+	FadeAtMs     int    `json:"fade_at_ms"`
+	EndAtMs      int    `json:"end_at_ms"`
+}
+
+type Interval struct {
+	FromMs int `json:"from_ms"`
+	ToMs   int `json:"to_ms"`
+}
+
+// SiteCoverage - покрытие одной цели execute
+type SiteCoverage struct {
+	TargetID int64      `json:"target_id"`
+	Coverage []Interval `json:"coverage"`
+	Gaps     []Interval `json:"gaps"`
+}
+
+type Timeline struct {
+	Events   []*TimelineEvent `json:"events"`
+	Sites    []*SiteCoverage  `json:"sites"`
+	EntryMs  *int             `json:"entry_ms,omitempty"`
+	Warnings []string         `json:"warnings,omitempty"`
+}
+
+// BuildTimeline считает когда каждая граната execute срабатывает и заканчивается.
+// Покрытие считается отдельно для каждой цели: смок на одной точке не закрывает другую.
+// Покрытие цели - объединение интервалов полного эффекта ее гранат с Covers,
+// разрывы ищутся от начала покрытия до entryMs (если задан) или до конца покрытия.
+// Цель без покрытия целиком считается разрывом, гранаты без цели в покрытии не участвуют
+func BuildTimeline(execute *Execute, timings map[string]*GrenadeTiming, entryMs *int) *Timeline {
+	timeline := &Timeline{
+		Events:  []*TimelineEvent{},
+		Sites:   []*SiteCoverage{},
+		EntryMs: entryMs,
+	}
+
+	// covering - интервалы покрытия по целям, в map есть все цели execute, даже без покрытия
+	covering := make(map[int64][]Interval)
+	endMs := 0
+
+	for i, item := range execute.Items {
+		event := &TimelineEvent{
+			Position:   i,
+			GrenadeID:  item.GrenadeID,
+			PlayerSlot: item.PlayerSlot,
+			ThrowAtMs:  item.ThrowOffsetMs,
+		}
+		if item.Grenade != nil {
+			event.TargetID = item.Grenade.TargetID
+			event.Title = item.Grenade.Title
+			event.Type = item.Grenade.Type
+		}
+
+		timing, ok := timings[event.Type]
+		if !ok {
+			timing = &GrenadeTiming{Type: event.Type}
+			timeline.Warnings = append(timeline.Warnings, fmt.Sprintf("no timings configured for type %q, grenade %d", event.Type, item.GrenadeID))
+		}
+
+		event.DetonateAtMs = event.ThrowAtMs + timing.DetonateMs
+		event.FullAtMs = event.DetonateAtMs + timing.BloomMs
+		event.FadeAtMs = event.FullAtMs + timing.EffectMs
+		event.EndAtMs = event.FadeAtMs + timing.FadeMs
+
+		if event.EndAtMs > endMs {
+			endMs = event.EndAtMs
+		}
+
+		if event.TargetID != 0 {
+			intervals := covering[event.TargetID]
+			if timing.Covers && event.FadeAtMs > event.FullAtMs {
+				intervals = append(intervals, Interval{FromMs: event.FullAtMs, ToMs: event.FadeAtMs})
+			}
+			covering[event.TargetID] = intervals
+		}
+
+		timeline.Events = append(timeline.Events, event)
+	}
+
+	sort.SliceStable(timeline.Events, func(i, j int) bool {
+		return timeline.Events[i].ThrowAtMs < timeline.Events[j].ThrowAtMs
+	})
+
+	if len(covering) == 0 && len(execute.Items) > 0 {
+		timeline.Warnings = append(timeline.Warnings, "no grenades have a target, site coverage is not calculated")
+	}
+
+	for targetID, intervals := range covering {
+		coverage := mergeIntervals(intervals)
+
+		// без entryMs цель без покрытия открыта до конца execute
+		untilMs := entryMs
+		if len(coverage) == 0 && untilMs == nil {
+			untilMs = &endMs
+		}
+
+		timeline.Sites = append(timeline.Sites, &SiteCoverage{
+			TargetID: targetID,
+			Coverage: coverage,
+			Gaps:     coverageGaps(coverage, untilMs),
+		})
+	}
+
+	sort.Slice(timeline.Sites, func(i, j int) bool {
+		return timeline.Sites[i].TargetID < timeline.Sites[j].TargetID
+	})
+
+	return timeline
+}
+
+// coverageGaps возвращает разрывы между интервалами coverage, отсортированными mergeIntervals,
+// и разрыв от конца покрытия до entryMs. Без покрытия разрыв - весь отрезок до entryMs
+func coverageGaps(coverage []Interval, entryMs *int) []Interval {
+	gaps := []Interval{}
+
+	if len(coverage) == 0 {
+		if entryMs != nil && *entryMs > 0 {
+			gaps = append(gaps, Interval{FromMs: 0, ToMs: *entryMs})
+		}
+		return gaps
+	}
+
+	for i := 1; i < len(coverage); i++ {
+		from, to := coverage[i-1].ToMs, coverage[i].FromMs
+		if entryMs != nil && to > *entryMs {
+			to = *entryMs
+		}
+		if from < to {
+			gaps = append(gaps, Interval{FromMs: from, ToMs: to})
+		}
+	}
+
+	// покрытие должно держаться до выхода
+	last := coverage[len(coverage)-1]
+	if entryMs != nil && last.ToMs < *entryMs {
+		gaps = append(gaps, Interval{FromMs: last.ToMs, ToMs: *entryMs})
+	}
+
+	return gaps
+}
+
+func mergeIntervals(intervals []Interval) []Interval {
+	merged := []Interval{}
+
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].FromMs < intervals[j].FromMs
+	})
+
+	for _, interval := range intervals {
+		n := len(merged)
+		if n > 0 && interval.FromMs <= merged[n-1].ToMs {
+			if interval.ToMs > merged[n-1].ToMs {
+				merged[n-1].ToMs = interval.ToMs
+			}
+			continue
+		}
+		merged = append(merged, interval)
+	}
+
+	return merged
+}
@@ -0,0 +1,152 @@
+package data
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+var testTimings = map[string]*GrenadeTiming{
+	"smoke": {Type: "smoke", DetonateMs: 1000, BloomMs: 1000, EffectMs: 15000, FadeMs: 3000, Covers: true},
+	"flash": {Type: "flash", DetonateMs: 1500, Covers: false},
+}
+
+func testExecuteItem(grenadeID, targetID int64, grenadeType string, throwOffsetMs int) *ExecuteItem {
+	return &ExecuteItem{
+		GrenadeID:     grenadeID,
+		PlayerSlot:    1,
+		ThrowOffsetMs: throwOffsetMs,
+		Grenade:       &Grenade{ID: grenadeID, TargetID: targetID, Type: grenadeType},
+	}
+}
+
+func TestBuildTimelineCoveragePerSite(t *testing.T) {
+	execute := &Execute{Items: []*ExecuteItem{
+		// цель 1: 2000-17000 и 20000-35000, разрыв 17000-20000
+		testExecuteItem(1, 1, "smoke", 0),
+		testExecuteItem(2, 1, "smoke", 18000),
+		// цель 2 закрывает разрыв цели 1 по времени, но не по месту
+		testExecuteItem(3, 2, "smoke", 10000),
+		testExecuteItem(4, 2, "flash", 0),
+		// на цель 3 только флешка, цель открыта целиком
+		testExecuteItem(5, 3, "flash", 0),
+		// смок без цели не закрывает ни одну цель
+		testExecuteItem(6, 0, "smoke", 15000),
+	}}
+
+	entryMs := 30000
+	timeline := BuildTimeline(execute, testTimings, &entryMs)
+
+	want := []*SiteCoverage{
+		{
+			TargetID: 1,
+			Coverage: []Interval{{2000, 17000}, {20000, 35000}},
+			Gaps:     []Interval{{17000, 20000}},
+		},
+		{
+			TargetID: 2,
+			Coverage: []Interval{{12000, 27000}},
+			Gaps:     []Interval{{27000, 30000}},
+		},
+		{
+			TargetID: 3,
+			Coverage: []Interval{},
+			Gaps:     []Interval{{0, 30000}},
+		},
+	}
+
+	if !reflect.DeepEqual(timeline.Sites, want) {
+		t.Errorf("sites = %s, want %s", sitesString(timeline.Sites), sitesString(want))
+	}
+
+	if len(timeline.Events) != 6 {
+		t.Errorf("events = %d, want 6", len(timeline.Events))
+	}
+}
+
+func TestBuildTimelineSiteWithoutCoverage(t *testing.T) {
+	execute := &Execute{Items: []*ExecuteItem{
+		testExecuteItem(1, 1, "flash", 0),
+		testExecuteItem(2, 1, "molotov", 0),
+	}}
+
+	tests := []struct {
+		name    string
+		entryMs *int
+		gaps    []Interval
+	}{
+		// без entry_ms цель открыта до конца execute - срабатывания флешки
+		{"no entry", nil, []Interval{{0, 1500}}},
+		{"entry", intPtr(5000), []Interval{{0, 5000}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeline := BuildTimeline(execute, testTimings, tt.entryMs)
+
+			want := []*SiteCoverage{{TargetID: 1, Coverage: []Interval{}, Gaps: tt.gaps}}
+			if !reflect.DeepEqual(timeline.Sites, want) {
+				t.Errorf("sites = %s, want %s", sitesString(timeline.Sites), sitesString(want))
+			}
+
+			// нет таймингов molotov
+			if len(timeline.Warnings) != 1 {
+				t.Errorf("warnings = %v, want 1", timeline.Warnings)
+			}
+		})
+	}
+}
+
+func TestBuildTimelineWithoutTargets(t *testing.T) {
+	execute := &Execute{Items: []*ExecuteItem{
+		testExecuteItem(1, 0, "smoke", 0),
+	}}
+
+	timeline := BuildTimeline(execute, testTimings, intPtr(5000))
+
+	if len(timeline.Sites) != 0 {
+		t.Errorf("sites = %s, want none", sitesString(timeline.Sites))
+	}
+
+	if len(timeline.Warnings) != 1 {
+		t.Errorf("warnings = %v, want 1", timeline.Warnings)
+	}
+}
+
+func TestCoverageGaps(t *testing.T) {
+	tests := []struct {
+		name     string
+		coverage []Interval
+		entryMs  *int
+		want     []Interval
+	}{
+		{"no coverage", []Interval{}, intPtr(25), []Interval{{0, 25}}},
+		{"no coverage without entry", nil, nil, []Interval{}},
+		{"no entry", []Interval{{0, 10}, {20, 30}}, nil, []Interval{{10, 20}}},
+		{"gap cut at entry", []Interval{{0, 10}, {20, 30}}, intPtr(15), []Interval{{10, 15}}},
+		{"gap after entry ignored", []Interval{{0, 10}, {20, 30}}, intPtr(5), []Interval{}},
+		{"coverage ends before entry", []Interval{{0, 10}}, intPtr(25), []Interval{{10, 25}}},
+		{"covered until entry", []Interval{{0, 30}}, intPtr(25), []Interval{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := coverageGaps(tt.coverage, tt.entryMs)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("gaps = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func sitesString(sites []*SiteCoverage) string {
+	s := ""
+	for _, site := range sites {
+		s += fmt.Sprintf("%+v ", *site)
+	}
+	return s
+}
+
+func intPtr(v int) *int {
+	return &v
+}
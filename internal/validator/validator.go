@@ -1,5 +1,9 @@
 package validator
 
+import "regexp"
+
+var EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
+
 type Validator struct {
 	Erorrs map[string]string
 }
@@ -31,4 +35,34 @@ func (v *Validator) In(value string, keys []string) bool {
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
+
+func (v *Validator) Matches(value string, rx *regexp.Regexp) bool {
+	return rx.MatchString(value)
+}
+
+// Unique проверяет, что в values нет повторов
+func Unique[T comparable](values []T) bool {
+	seen := make(map[T]bool, len(values))
+	for _, value := range values {
+		if seen[value] {
+			return false
+		}
+		seen[value] = true
+	}
+	return true
+}
+
+// Rule - правило валидации значения типа T, Check возвращает false если значение не проходит правило
+type Rule[T any] struct {
+	Key     string
+	Message string
+	Check   func(value T) bool
+}
+
+// Apply проверяет значение по всем правилам
+func Apply[T any](v *Validator, value T, rules []Rule[T]) {
+	for _, rule := range rules {
+		v.Check(rule.Check(value), rule.Key, rule.Message)
+	}
+}
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS grenades_search_idx;
+
+ALTER TABLE grenades DROP COLUMN IF EXISTS search;
\ No newline at end of file
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS grenades_title_trgm_idx;
+DROP INDEX IF EXISTS grenades_map_trgm_idx;
+
+DROP TABLE IF EXISTS callouts;
\ No newline at end of file
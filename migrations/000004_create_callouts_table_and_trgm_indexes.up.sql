@@ -0,0 +1,12 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE TABLE IF NOT EXISTS callouts (
+    id bigserial PRIMARY KEY,
+    map varchar(30) NOT NULL,
+    name text NOT NULL,
+    UNIQUE (map, name)
+);
+
+CREATE INDEX IF NOT EXISTS grenades_title_trgm_idx ON grenades USING GIN (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS grenades_map_trgm_idx ON grenades USING GIN (map gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS callouts_name_trgm_idx ON callouts USING GIN (name gin_trgm_ops);
\ No newline at end of file
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS grenades_created_at_idx;
+DROP INDEX IF EXISTS grenades_title_idx;
+
+ALTER TABLE grenades DROP COLUMN IF EXISTS created_at;
\ No newline at end of file
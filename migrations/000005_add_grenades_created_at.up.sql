@@ -0,0 +1,4 @@
+ALTER TABLE grenades ADD COLUMN IF NOT EXISTS created_at timestamp(6) with time zone NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS grenades_created_at_idx ON grenades (created_at, id);
+CREATE INDEX IF NOT EXISTS grenades_title_idx ON grenades (title, id);
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS tags (
+    id bigserial PRIMARY KEY,
+    name varchar(50) NOT NULL UNIQUE,
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS grenades_tags (
+    grenade_id bigint NOT NULL REFERENCES grenades ON DELETE CASCADE,
+    tag_id bigint NOT NULL REFERENCES tags ON DELETE CASCADE,
+    PRIMARY KEY (grenade_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS grenades_tags_tag_id_idx ON grenades_tags (tag_id);
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS execute_items;
+DROP TABLE IF EXISTS executes;
\ No newline at end of file
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS executes (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    map varchar(30) NOT NULL,
+    side varchar(30) NOT NULL,
+    description text NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS execute_items (
+    execute_id bigint NOT NULL REFERENCES executes ON DELETE CASCADE,
+    position integer NOT NULL,
+    grenade_id bigint NOT NULL REFERENCES grenades ON DELETE CASCADE,
+    player_slot integer NOT NULL,
+    throw_offset_ms integer NOT NULL DEFAULT 0,
+    PRIMARY KEY (execute_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS execute_items_grenade_id_idx ON execute_items (grenade_id);
\ No newline at end of file
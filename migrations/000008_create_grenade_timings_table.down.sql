@@ -0,0 +1 @@
+DROP TABLE IF EXISTS grenade_timings;
\ No newline at end of file
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS grenade_timings (
+    type varchar(30) PRIMARY KEY,
+    detonate_ms integer NOT NULL,
+    bloom_ms integer NOT NULL DEFAULT 0,
+    effect_ms integer NOT NULL DEFAULT 0,
+    fade_ms integer NOT NULL DEFAULT 0,
+    covers boolean NOT NULL DEFAULT false
+);
+
+INSERT INTO grenade_timings (type, detonate_ms, bloom_ms, effect_ms, fade_ms, covers) VALUES
+    ('smoke', 1500, 1000, 17000, 1500, true),
+    ('molotov', 1500, 0, 7000, 0, false),
+    ('flash', 1600, 0, 0, 0, false),
+    ('he', 1600, 0, 0, 0, false),
+    ('decoy', 2000, 0, 15000, 0, false)
+ON CONFLICT (type) DO NOTHING;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS grenade_prices;
\ No newline at end of file
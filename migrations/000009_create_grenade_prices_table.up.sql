@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS grenade_prices (
+    type varchar(30) PRIMARY KEY,
+    price integer NOT NULL,
+    max_per_player integer NOT NULL DEFAULT 1
+);
+
+INSERT INTO grenade_prices (type, price, max_per_player) VALUES
+    ('smoke', 300, 1),
+    ('molotov', 400, 1),
+    ('incendiary', 500, 1),
+    ('flash', 200, 2),
+    ('he', 300, 1),
+    ('decoy', 50, 1)
+ON CONFLICT (type) DO NOTHING;
\ No newline at end of file
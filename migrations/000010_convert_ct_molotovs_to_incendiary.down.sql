@@ -0,0 +1,4 @@
+DELETE FROM grenade_timings WHERE type = 'incendiary';
+
+UPDATE grenades SET type = 'molotov', version = version + 1
+WHERE type = 'incendiary';
\ No newline at end of file
@@ -0,0 +1,6 @@
+UPDATE grenades SET type = 'incendiary', version = version + 1
+WHERE type = 'molotov' AND side = 'CT';
+
+INSERT INTO grenade_timings (type, detonate_ms, bloom_ms, effect_ms, fade_ms, covers)
+VALUES ('incendiary', 1500, 0, 7000, 0, false)
+ON CONFLICT (type) DO NOTHING;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE grenades DROP COLUMN IF EXISTS target_id;
+
+DROP TABLE IF EXISTS targets;
\ No newline at end of file
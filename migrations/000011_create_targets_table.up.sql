@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS targets (
+    id bigserial PRIMARY KEY,
+    map varchar(30) NOT NULL,
+    title text NOT NULL,
+    description text NOT NULL DEFAULT '',
+    version integer NOT NULL DEFAULT 1
+);
+
+ALTER TABLE grenades ADD COLUMN IF NOT EXISTS target_id bigint REFERENCES targets ON DELETE RESTRICT;
+
+CREATE INDEX IF NOT EXISTS grenades_target_id_idx ON grenades (target_id);
\ No newline at end of file
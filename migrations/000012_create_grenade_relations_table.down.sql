@@ -0,0 +1 @@
+DROP TABLE IF EXISTS grenade_relations;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS grenade_relations (
+    id bigserial PRIMARY KEY,
+    from_grenade_id bigint NOT NULL REFERENCES grenades ON DELETE CASCADE,
+    to_grenade_id bigint NOT NULL REFERENCES grenades ON DELETE CASCADE,
+    kind varchar(30) NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    UNIQUE (from_grenade_id, to_grenade_id, kind),
+    CHECK (from_grenade_id <> to_grenade_id)
+);
+
+CREATE INDEX IF NOT EXISTS grenade_relations_to_grenade_id_idx ON grenade_relations (to_grenade_id);
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS grenades_map_verification_status_idx;
+
+ALTER TABLE grenades DROP COLUMN IF EXISTS verified_at;
+ALTER TABLE grenades DROP COLUMN IF EXISTS verified_build;
+ALTER TABLE grenades DROP COLUMN IF EXISTS verification_status;
\ No newline at end of file
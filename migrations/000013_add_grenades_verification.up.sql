@@ -0,0 +1,5 @@
+ALTER TABLE grenades ADD COLUMN IF NOT EXISTS verification_status varchar(30) NOT NULL DEFAULT 'unverified';
+ALTER TABLE grenades ADD COLUMN IF NOT EXISTS verified_build text NOT NULL DEFAULT '';
+ALTER TABLE grenades ADD COLUMN IF NOT EXISTS verified_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS grenades_map_verification_status_idx ON grenades (map, verification_status);
\ No newline at end of file
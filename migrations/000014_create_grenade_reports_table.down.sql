@@ -0,0 +1 @@
+DROP TABLE IF EXISTS grenade_reports;
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS grenade_reports (
+    id bigserial PRIMARY KEY,
+    grenade_id bigint NOT NULL REFERENCES grenades ON DELETE CASCADE,
+    reason text NOT NULL,
+    build text NOT NULL DEFAULT '',
+    status varchar(30) NOT NULL DEFAULT 'open',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    resolved_at timestamp(0) with time zone,
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS grenade_reports_grenade_id_status_idx ON grenade_reports (grenade_id, status);
+CREATE INDEX IF NOT EXISTS grenade_reports_status_idx ON grenade_reports (status);
\ No newline at end of file
@@ -0,0 +1,20 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role text NOT NULL DEFAULT 'player';
+
+CREATE TABLE IF NOT EXISTS roles_permissions (
+    role text NOT NULL,
+    permission text NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+-- первого админа назначают вручную: UPDATE users SET role = 'admin' WHERE email = '...'
+INSERT INTO roles_permissions (role, permission) VALUES
+    ('editor', 'grenades:write'),
+    ('editor', 'images:write'),
+    ('moderator', 'grenades:write'),
+    ('moderator', 'images:write'),
+    ('moderator', 'moderation:manage'),
+    ('admin', 'grenades:write'),
+    ('admin', 'images:write'),
+    ('admin', 'moderation:manage'),
+    ('admin', 'users:manage')
+ON CONFLICT DO NOTHING;
\ No newline at end of file
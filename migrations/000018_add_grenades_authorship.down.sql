@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS grenades_created_by_idx;
+
+ALTER TABLE grenades DROP COLUMN IF EXISTS credit;
+ALTER TABLE grenades DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE grenades DROP COLUMN IF EXISTS created_by;
\ No newline at end of file
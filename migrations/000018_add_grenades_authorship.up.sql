@@ -0,0 +1,5 @@
+ALTER TABLE grenades ADD COLUMN IF NOT EXISTS created_by bigint REFERENCES users ON DELETE SET NULL;
+ALTER TABLE grenades ADD COLUMN IF NOT EXISTS updated_by bigint REFERENCES users ON DELETE SET NULL;
+ALTER TABLE grenades ADD COLUMN IF NOT EXISTS credit text NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS grenades_created_by_idx ON grenades (created_by);
\ No newline at end of file
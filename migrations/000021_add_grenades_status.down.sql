@@ -0,0 +1,8 @@
+DELETE FROM roles_permissions WHERE permission = 'grenades:submit';
+
+DROP INDEX IF EXISTS grenades_status_idx;
+
+ALTER TABLE grenades DROP COLUMN IF EXISTS reviewed_at;
+ALTER TABLE grenades DROP COLUMN IF EXISTS reviewed_by;
+ALTER TABLE grenades DROP COLUMN IF EXISTS review_comment;
+ALTER TABLE grenades DROP COLUMN IF EXISTS status;
\ No newline at end of file
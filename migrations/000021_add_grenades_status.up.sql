@@ -0,0 +1,16 @@
+-- существующие раскидки уже опубликованы, новые создаются черновиками
+ALTER TABLE grenades ADD COLUMN IF NOT EXISTS status varchar(20) NOT NULL DEFAULT 'published';
+ALTER TABLE grenades ALTER COLUMN status SET DEFAULT 'draft';
+ALTER TABLE grenades ADD COLUMN IF NOT EXISTS review_comment text NOT NULL DEFAULT '';
+ALTER TABLE grenades ADD COLUMN IF NOT EXISTS reviewed_by bigint REFERENCES users ON DELETE SET NULL;
+ALTER TABLE grenades ADD COLUMN IF NOT EXISTS reviewed_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS grenades_status_idx ON grenades (status);
+
+-- игроки могут предлагать раскидки, публикует модератор
+INSERT INTO roles_permissions (role, permission) VALUES
+    ('player', 'grenades:submit'),
+    ('editor', 'grenades:submit'),
+    ('moderator', 'grenades:submit'),
+    ('admin', 'grenades:submit')
+ON CONFLICT DO NOTHING;
\ No newline at end of file
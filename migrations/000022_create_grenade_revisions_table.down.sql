@@ -0,0 +1 @@
+DROP TABLE IF EXISTS grenade_revisions;
\ No newline at end of file
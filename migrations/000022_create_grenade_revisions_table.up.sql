@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS grenade_revisions (
+    id bigserial PRIMARY KEY,
+    grenade_id bigint NOT NULL REFERENCES grenades ON DELETE CASCADE,
+    version integer NOT NULL,
+    data jsonb NOT NULL,
+    editor_id bigint REFERENCES users ON DELETE SET NULL,
+    note text NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    UNIQUE (grenade_id, version)
+);
+
+-- текущее состояние существующих раскидок становится их первой ревизией
+INSERT INTO grenade_revisions (grenade_id, version, data, editor_id, note)
+SELECT id, version, to_jsonb(grenades) - 'search', updated_by, 'initial revision'
+FROM grenades
+ON CONFLICT DO NOTHING;
\ No newline at end of file
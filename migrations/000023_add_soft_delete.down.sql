@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS images_deleted_at_idx;
+DROP INDEX IF EXISTS grenades_deleted_at_idx;
+
+ALTER TABLE images DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE images DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE grenades DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE grenades DROP COLUMN IF EXISTS deleted_at;
\ No newline at end of file
@@ -0,0 +1,23 @@
+DELETE FROM callouts
+WHERE (map, name) IN (
+    VALUES ('mirage', 'A Site'), ('mirage', 'B Site'), ('mirage', 'Mid'), ('mirage', 'Palace'), ('mirage', 'Ramp'),
+    ('mirage', 'Tetris'), ('mirage', 'Jungle'), ('mirage', 'Connector'), ('mirage', 'Stairs'), ('mirage', 'CT Spawn'),
+    ('mirage', 'Ticket Booth'), ('mirage', 'Window'), ('mirage', 'Short'), ('mirage', 'Apartments'), ('mirage', 'Market'),
+    ('mirage', 'Van'), ('mirage', 'Bench'), ('mirage', 'Top Mid'), ('mirage', 'Firebox'), ('mirage', 'Triple'),
+    ('dust2', 'A Site'), ('dust2', 'B Site'), ('dust2', 'Long A'), ('dust2', 'Short A'), ('dust2', 'Catwalk'),
+    ('dust2', 'Mid'), ('dust2', 'Mid Doors'), ('dust2', 'Xbox'), ('dust2', 'Upper Tunnels'), ('dust2', 'Lower Tunnels'),
+    ('dust2', 'B Doors'), ('dust2', 'B Window'), ('dust2', 'Car'), ('dust2', 'Pit'), ('dust2', 'Goose'),
+    ('dust2', 'CT Spawn'), ('dust2', 'T Spawn'), ('dust2', 'Outside Long'),
+    ('inferno', 'A Site'), ('inferno', 'B Site'), ('inferno', 'Banana'), ('inferno', 'Apartments'), ('inferno', 'Mid'),
+    ('inferno', 'Pit'), ('inferno', 'Library'), ('inferno', 'Arch'), ('inferno', 'Balcony'), ('inferno', 'Graveyard'),
+    ('inferno', 'Coffins'), ('inferno', 'Construction'), ('inferno', 'CT Spawn'), ('inferno', 'Boiler'), ('inferno', 'Truck'),
+    ('nuke', 'A Site'), ('nuke', 'B Site'), ('nuke', 'Outside'), ('nuke', 'Ramp'), ('nuke', 'Lobby'),
+    ('nuke', 'Hut'), ('nuke', 'Heaven'), ('nuke', 'Hell'), ('nuke', 'Vents'), ('nuke', 'Secret'),
+    ('nuke', 'Squeaky'), ('nuke', 'Silo'), ('nuke', 'Garage'), ('nuke', 'Mini'),
+    ('ancient', 'A Site'), ('ancient', 'B Site'), ('ancient', 'Mid'), ('ancient', 'Donut'), ('ancient', 'Cave'),
+    ('ancient', 'Temple'), ('ancient', 'Ramp'), ('ancient', 'Elbow'), ('ancient', 'CT Spawn'), ('ancient', 'Main'),
+    ('anubis', 'A Site'), ('anubis', 'B Site'), ('anubis', 'Mid'), ('anubis', 'Canal'), ('anubis', 'Connector'),
+    ('anubis', 'Palace'), ('anubis', 'Bridge'), ('anubis', 'Heaven'), ('anubis', 'Main'), ('anubis', 'CT Spawn'),
+    ('vertigo', 'A Site'), ('vertigo', 'B Site'), ('vertigo', 'Ramp'), ('vertigo', 'Mid'), ('vertigo', 'Stairs'),
+    ('vertigo', 'Elevator'), ('vertigo', 'Scaffolding'), ('vertigo', 'Sandbags'), ('vertigo', 'CT Spawn'), ('vertigo', 'T Spawn')
+);
\ No newline at end of file